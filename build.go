@@ -30,7 +30,7 @@ var buildCmd = &cobra.Command{
 		tag, _ := cmd.Flags().GetString("tag")
 
 		if project == "" {
-			fmt.Println("Error: --project is required")
+			logError("Error: --project is required")
 			return
 		}
 
@@ -39,14 +39,14 @@ var buildCmd = &cobra.Command{
 		switch actionName {
 		case "docker":
 			if err := deployDocker(project, tag); err != nil {
-				fmt.Println("Error deploying to Docker:", err)
+				logError("Error deploying to Docker:", err)
 			}
 		case "zip":
 			if err := deployZip(project); err != nil {
-				fmt.Println("Error deploying as Zip:", err)
+				logError("Error deploying as Zip:", err)
 			}
 		default:
-			fmt.Println("Invalid provider. Use 'docker', 'zip', 'aws', or 'google'.")
+			logError("Invalid provider. Use 'docker', 'zip', 'aws', or 'google'.")
 		}
 	},
 }
@@ -66,6 +66,7 @@ func deployDocker(project, tag string) error {
 		return fmt.Errorf("error creating Docker client: %w", err)
 	}
 
+	logDebug("Building docker image from", projectDir)
 	tar, err := archive.TarWithOptions(projectDir, &archive.TarOptions{})
 	if err != nil {
 		return fmt.Errorf("error creating tar archive: %w", err)
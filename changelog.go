@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	markdownLink   = regexp.MustCompile(`\[([^\]]+)\]\(([^)]+)\)`)
+	markdownBold   = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	markdownHeader = regexp.MustCompile(`^#{1,6}\s+`)
+	markdownBullet = regexp.MustCompile(`^(\s*)[-*+]\s+`)
+	markdownImage  = regexp.MustCompile(`!\[[^\]]*\]\([^)]+\)`)
+)
+
+// showChangelog prints the release notes of every release after installed
+// up to and including target, so breaking changes are seen before upgrading
+func showChangelog(repo, installed, target string) {
+	releases, err := getRepoReleases(repo)
+	if err != nil {
+		logDebug("Skipping changelog:", err)
+		return
+	}
+
+	var notes []githubRelease
+	for _, r := range releases {
+		if compareVersions(r.TagName, target) > 0 {
+			continue
+		}
+		if installed != "" && compareVersions(r.TagName, installed) <= 0 {
+			continue
+		}
+		if strings.TrimSpace(r.Body) != "" {
+			notes = append(notes, r)
+		}
+	}
+	if len(notes) == 0 {
+		return
+	}
+
+	fmt.Println(Cyan + "Changelog" + Reset)
+	for _, r := range notes {
+		fmt.Println()
+		fmt.Println(Green + r.TagName + Reset)
+		fmt.Println(renderMarkdown(r.Body))
+	}
+	fmt.Println()
+}
+
+// renderMarkdown turns the markdown of release notes into plain terminal
+// text with colored headings, it does not try to support all of markdown
+func renderMarkdown(markdown string) string {
+	var out []string
+	inCode := false
+	for _, line := range strings.Split(strings.ReplaceAll(markdown, "\r\n", "\n"), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			out = append(out, Gray+"    "+line+Reset)
+			continue
+		}
+
+		line = markdownImage.ReplaceAllString(line, "")
+		line = markdownLink.ReplaceAllString(line, "$1 ($2)")
+		line = markdownBold.ReplaceAllString(line, "$1$2")
+		line = strings.ReplaceAll(line, "`", "")
+
+		switch {
+		case markdownHeader.MatchString(line):
+			line = Yellow + markdownHeader.ReplaceAllString(line, "") + Reset
+		case markdownBullet.MatchString(line):
+			line = markdownBullet.ReplaceAllString(line, "$1  • ")
+		}
+		out = append(out, line)
+	}
+	return strings.TrimRight(strings.Join(out, "\n"), "\n")
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var completionCmd = &cobra.Command{
+	Use:   "completion [bash|zsh|fish|powershell]",
+	Short: "Generate shell completion script",
+	Long: `Generate the autocompletion script for the specified shell.
+
+  Bash:
+    $ source <(apito completion bash)
+
+  Zsh:
+    $ apito completion zsh > "${fpath[1]}/_apito"
+
+  Fish:
+    $ apito completion fish | source
+
+  PowerShell:
+    PS> apito completion powershell | Out-String | Invoke-Expression`,
+	DisableFlagsInUseLine: true,
+	ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
+	Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		switch args[0] {
+		case "bash":
+			return cmd.Root().GenBashCompletionV2(os.Stdout, true)
+		case "zsh":
+			return cmd.Root().GenZshCompletion(os.Stdout)
+		case "fish":
+			return cmd.Root().GenFishCompletion(os.Stdout, true)
+		case "powershell":
+			return cmd.Root().GenPowerShellCompletionWithDesc(os.Stdout)
+		}
+		return nil
+	},
+}
+
+// completeProjects completes project names from the directories in ~/.apito
+func completeProjects(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	files, err := os.ReadDir(filepath.Join(homeDir, ".apito"))
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var projects []string
+	for _, f := range files {
+		if !f.IsDir() || !strings.HasPrefix(f.Name(), toComplete) {
+			continue
+		}
+		// only directories with a config file are projects
+		if _, err := os.Stat(filepath.Join(homeDir, ".apito", f.Name(), ConfigFile)); err == nil {
+			projects = append(projects, f.Name())
+		}
+	}
+
+	return projects, cobra.ShellCompDirectiveNoFileComp
+}
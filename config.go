@@ -0,0 +1,421 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+)
+
+// ConfigBackupDir is created next to a config file and holds its last
+// ConfigBackups versions, named .env.<timestamp>
+const ConfigBackupDir = ".backups"
+const ConfigBackups = 10
+
+const configBackupTimeFormat = "20060102-150405.000"
+
+// globalConfigKeys are the keys of ~/.apito/.env with a check of their value
+var globalConfigKeys = map[string]func(string) error{
+	"TOKEN":            nil,
+	"TELEMETRY":        oneOf("enabled", "disabled"),
+	"TIMEOUT":          validateDuration,
+	"DOWNLOAD_TIMEOUT": validateDuration,
+	"UPDATE_CHECK":     oneOf("true", "false"),
+	"UPDATE_CHECK_TTL": validateDuration,
+	"CONFIRM_LEVEL":    oneOf("all", "destructive", "none"),
+	"DEFAULT_PROJECT":  validateProjectExists,
+	"DOWNLOAD_MIRROR":  validateURL,
+	"LOGIN_URL":        validateURL,
+	"CONSOLE_VERSION":  nil,
+}
+
+func init() {
+	configDoctorCmd.Flags().Bool("fix", false, "Repair the problems that can be repaired")
+
+	configRestoreCmd.Flags().Bool("list", false, "List the backups instead of restoring one")
+	configRestoreCmd.ValidArgsFunction = completeConfigBackups
+
+	configCmd.AddCommand(configDoctorCmd)
+	configCmd.AddCommand(configRestoreCmd)
+}
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Manage the cli and project configs",
+	Long:  `Manage the cli config in ~/.apito/.env and the project configs in ~/.apito/<project>/.env.`,
+}
+
+var configDoctorCmd = &cobra.Command{
+	Use:         "doctor",
+	Short:       "Check the cli and project configs for problems",
+	Long:        `Check ~/.apito/.env for unknown keys and invalid values and every project config, or only the one of --project, for legacy keys, missing keys, invalid URLs and a missing engine binary. With --fix the problems are repaired, asking for values that cannot be guessed.`,
+	Annotations: map[string]string{lockAnnotation: "true", explicitProjectAnnotation: "true"},
+	Run: func(cmd *cobra.Command, args []string) {
+		project, _ := cmd.Flags().GetString("project")
+		fix, _ := cmd.Flags().GetBool("fix")
+
+		// the unknown keys are reported below
+		unknownKeysWarning.Do(func() {})
+
+		apitoDir, err := getApitoDir()
+		if err != nil {
+			logError("Error:", err)
+			return
+		}
+
+		checks := map[string][]projectIssue{}
+		var scopes []string
+
+		if project == "" {
+			scopes = append(scopes, "cli config")
+			checks["cli config"], err = findGlobalConfigIssues()
+			if err != nil {
+				logError("Error checking cli config:", err)
+				return
+			}
+		}
+
+		projects := []string{project}
+		if project == "" {
+			projects = listProjectNames(apitoDir)
+		}
+		for _, p := range projects {
+			scope := "project " + p
+			scopes = append(scopes, scope)
+			checks[scope], err = findProjectIssues(p, filepath.Join(apitoDir, p), map[string]string{})
+			if err != nil {
+				logError("Error checking project", p+":", err)
+				return
+			}
+		}
+
+		problems, fixed := 0, 0
+		for _, scope := range scopes {
+			if len(checks[scope]) == 0 {
+				fmt.Println(Green+"✓"+Reset, scope)
+				continue
+			}
+			fmt.Println(Yellow+"!"+Reset, scope)
+			for _, issue := range checks[scope] {
+				problems++
+				fmt.Println("  - " + issue.Description)
+				if !fix {
+					continue
+				}
+				if issue.Repair == nil {
+					fmt.Println(Gray + "    cannot be repaired automatically" + Reset)
+					continue
+				}
+				if err := issue.Repair(); err != nil {
+					logError("    Error repairing:", err)
+					continue
+				}
+				fixed++
+				fmt.Println(Green + "    repaired" + Reset)
+			}
+		}
+
+		switch {
+		case problems == 0:
+			fmt.Println(Green + "No problems found" + Reset)
+		case !fix:
+			fmt.Printf("%d problem(s) found, run `apito config doctor --fix` to repair them\n", problems)
+		default:
+			fmt.Printf("%d of %d problem(s) repaired\n", fixed, problems)
+		}
+	},
+}
+
+var configRestoreCmd = &cobra.Command{
+	Use:         "restore [backup]",
+	Short:       "Roll a config back to a backup",
+	Long:        `Replace the project config, or the cli config without --project, with one of its backups. Without a backup name the backup is picked interactively, or the newest one is used. The replaced config is backed up as well.`,
+	Annotations: map[string]string{lockAnnotation: "true", explicitProjectAnnotation: "true"},
+	Args:        cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		project, _ := cmd.Flags().GetString("project")
+		list, _ := cmd.Flags().GetBool("list")
+
+		dir, err := getConfigDir(project)
+		if err != nil {
+			logError("Error:", err)
+			return
+		}
+
+		backups, err := listConfigBackups(dir)
+		if err != nil {
+			logError("Error reading backups:", err)
+			return
+		}
+		if len(backups) == 0 {
+			logError("No backups found in", filepath.Join(dir, ConfigBackupDir))
+			return
+		}
+
+		if list {
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+			fmt.Fprintln(w, "NAME\tCREATED")
+			for _, b := range backups {
+				fmt.Fprintf(w, "%s\t%s\n", b.Name, b.Time.Local().Format("2006-01-02 15:04:05"))
+			}
+			w.Flush()
+			return
+		}
+
+		var backup configBackup
+		switch {
+		case len(args) == 1:
+			found := false
+			for _, b := range backups {
+				if b.Name == args[0] {
+					backup, found = b, true
+					break
+				}
+			}
+			if !found {
+				logError("Error: backup not found:", args[0])
+				return
+			}
+		case isInteractive():
+			var items []string
+			for _, b := range backups {
+				items = append(items, b.Name+"  "+Gray+b.Time.Local().Format("2006-01-02 15:04:05")+Reset)
+			}
+			prompt := promptui.Select{
+				Label: "Select the backup to restore",
+				Items: items,
+			}
+			i, _, err := prompt.Run()
+			if err != nil {
+				return
+			}
+			backup = backups[i]
+		default:
+			backup = backups[0]
+			logInfo("Restoring the newest backup", backup.Name)
+		}
+
+		if !confirmSensitiveOperation(fmt.Sprintf("Replace %s with %s", filepath.Join(dir, ConfigFile), backup.Name), riskDestructive) {
+			return
+		}
+
+		if err := restoreConfigBackup(dir, backup); err != nil {
+			logError("Error restoring config:", err)
+			return
+		}
+		fmt.Println(Green+"Config restored from"+Reset, backup.Name)
+	},
+}
+
+// findGlobalConfigIssues reports unknown keys and invalid values in
+// ~/.apito/.env, invalid values are repaired by removing them so the
+// default is used
+func findGlobalConfigIssues() ([]projectIssue, error) {
+	config, err := getGlobalConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	var issues []projectIssue
+	for _, key := range unknownGlobalConfigKeys(config) {
+		issues = append(issues, projectIssue{
+			Description: fmt.Sprintf("unknown key %s", key),
+		})
+	}
+
+	var keys []string
+	for key := range globalConfigKeys {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		validate := globalConfigKeys[key]
+		value, ok := config[key]
+		if !ok || validate == nil {
+			continue
+		}
+		if err := validate(value); err != nil {
+			key := key
+			issues = append(issues, projectIssue{
+				Description: fmt.Sprintf("%s: %v, the default is used", key, err),
+				Repair: func() error {
+					apitoDir, err := getApitoDir()
+					if err != nil {
+						return err
+					}
+					config, err := getGlobalConfig()
+					if err != nil {
+						return err
+					}
+					delete(config, key)
+					return saveConfig(apitoDir, config)
+				},
+			})
+		}
+	}
+	return issues, nil
+}
+
+// unknownGlobalConfigKeys returns the keys of config this cli does not use,
+// usually typos
+func unknownGlobalConfigKeys(config map[string]string) []string {
+	var unknown []string
+	for key := range config {
+		if _, ok := globalConfigKeys[key]; !ok {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+func oneOf(values ...string) func(string) error {
+	return func(value string) error {
+		if !ArrayContains(values, value) {
+			return fmt.Errorf("invalid value %q, expected one of %s", value, strings.Join(values, ", "))
+		}
+		return nil
+	}
+}
+
+func validateDuration(value string) error {
+	if _, err := time.ParseDuration(value); err != nil {
+		return fmt.Errorf("invalid duration %q, e.g. 30s or 2m", value)
+	}
+	return nil
+}
+
+// listProjectNames returns the directories in ~/.apito with a project config
+func listProjectNames(apitoDir string) []string {
+	entries, err := os.ReadDir(apitoDir)
+	if err != nil {
+		return nil
+	}
+	var projects []string
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(apitoDir, e.Name(), ConfigFile)); err == nil {
+			projects = append(projects, e.Name())
+		}
+	}
+	return projects
+}
+
+type configBackup struct {
+	Name string
+	Path string
+	Time time.Time
+}
+
+// getConfigDir returns the directory of the project config, or ~/.apito for
+// the cli config when no project is given
+func getConfigDir(project string) (string, error) {
+	apitoDir, err := getApitoDir()
+	if err != nil {
+		return "", err
+	}
+	if project == "" {
+		return apitoDir, nil
+	}
+	return filepath.Join(apitoDir, project), nil
+}
+
+// backupConfig copies the config in dir to its backups directory and
+// removes the oldest backups beyond ConfigBackups
+func backupConfig(dir string) error {
+	data, err := os.ReadFile(filepath.Join(dir, ConfigFile))
+	if err != nil {
+		return err
+	}
+
+	backupDir := filepath.Join(dir, ConfigBackupDir)
+	if err := os.MkdirAll(backupDir, 0700); err != nil {
+		return err
+	}
+	name := ConfigFile + "." + time.Now().Format(configBackupTimeFormat)
+	if err := os.WriteFile(filepath.Join(backupDir, name), data, 0600); err != nil {
+		return err
+	}
+
+	backups, err := listConfigBackups(dir)
+	if err != nil {
+		return err
+	}
+	for i := ConfigBackups; i < len(backups); i++ {
+		os.Remove(backups[i].Path)
+	}
+	return nil
+}
+
+// listConfigBackups returns the backups of the config in dir, newest first
+func listConfigBackups(dir string) ([]configBackup, error) {
+	entries, err := os.ReadDir(filepath.Join(dir, ConfigBackupDir))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []configBackup
+	for _, e := range entries {
+		stamp, ok := strings.CutPrefix(e.Name(), ConfigFile+".")
+		if e.IsDir() || !ok {
+			continue
+		}
+		t, err := time.ParseInLocation(configBackupTimeFormat, stamp, time.Local)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, configBackup{
+			Name: e.Name(),
+			Path: filepath.Join(dir, ConfigBackupDir, e.Name()),
+			Time: t,
+		})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].Time.After(backups[j].Time)
+	})
+	return backups, nil
+}
+
+// restoreConfigBackup saves the backup as the config in dir, the current
+// config becomes a backup itself so the restore can be undone
+func restoreConfigBackup(dir string, backup configBackup) error {
+	data, err := os.ReadFile(backup.Path)
+	if err != nil {
+		return err
+	}
+	config, err := godotenv.Unmarshal(string(data))
+	if err != nil {
+		return fmt.Errorf("backup %s is not a valid config: %w", backup.Name, err)
+	}
+	return saveConfig(dir, config)
+}
+
+func completeConfigBackups(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	project, _ := cmd.Flags().GetString("project")
+	dir, err := getConfigDir(project)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	backups, _ := listConfigBackups(dir)
+
+	var names []string
+	for _, b := range backups {
+		if strings.HasPrefix(b.Name, toComplete) {
+			names = append(names, b.Name)
+		}
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/joho/godotenv"
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	configEditCmd.Flags().Bool("tui", false, "Edit the values in a form instead of $EDITOR")
+
+	configCmd.AddCommand(configEditCmd)
+}
+
+var configEditCmd = &cobra.Command{
+	Use:         "edit",
+	Short:       "Edit the cli or project config",
+	Long:        `Open ~/.apito/.env, or the config of --project, in $EDITOR. With --tui the known keys are shown in a form instead, values are checked as they are entered and secrets are masked. Nothing is written until the changes are saved, and the previous config is kept as a backup.`,
+	Annotations: map[string]string{lockAnnotation: "true", explicitProjectAnnotation: "true"},
+	Run: func(cmd *cobra.Command, args []string) {
+		project, _ := cmd.Flags().GetString("project")
+		tui, _ := cmd.Flags().GetBool("tui")
+
+		if !isInteractive() {
+			logError("Error: config edit needs a terminal, use `apito config set` in scripts")
+			return
+		}
+		if project != "" {
+			if err := validateProjectExists(project); err != nil {
+				logError("Error:", err)
+				return
+			}
+		}
+		dir, err := getConfigDir(project)
+		if err != nil {
+			logError("Error finding home directory:", err)
+			return
+		}
+		config, err := readConfigScope(project)
+		if err != nil {
+			logError("Error reading config:", err)
+			return
+		}
+
+		var edited map[string]string
+		if tui {
+			edited, err = editConfigForm(project, config)
+		} else {
+			edited, err = editConfigFile(project, config)
+		}
+		if err != nil {
+			logError("Error:", err)
+			return
+		}
+		if edited == nil {
+			return
+		}
+		if maps.Equal(edited, config) {
+			fmt.Println("No changes")
+			return
+		}
+		if err := saveConfig(dir, edited); err != nil {
+			logError("Error saving config:", err)
+			return
+		}
+		if !dryRun {
+			fmt.Println(Green+"Saved"+Reset, filepath.Join(dir, ConfigFile))
+		}
+	},
+}
+
+// editableConfigKeys returns the keys shown in the form, the known keys of
+// the scope and every key already set
+func editableConfigKeys(project string, config map[string]string) []string {
+	keys := map[string]bool{}
+	if project == "" {
+		for key := range globalConfigKeys {
+			keys[key] = true
+		}
+	} else {
+		for _, key := range append(requiredProjectKeys, "ENGINE_URL", "CONSOLE_URL") {
+			keys[key] = true
+		}
+	}
+	for key := range config {
+		keys[key] = true
+	}
+	return sortedKeys(keys)
+}
+
+// configField is an entry of the config form
+type configField struct {
+	Key     string
+	Value   string
+	Changed string
+}
+
+// editConfigForm lets the user pick keys and enter their values until the
+// changes are saved, it returns nil when they are discarded
+func editConfigForm(project string, config map[string]string) (map[string]string, error) {
+	edited := maps.Clone(config)
+	keys := editableConfigKeys(project, config)
+	const save, discard = "Save", "Discard changes"
+
+	cursor := 0
+	for {
+		fields := make([]configField, 0, len(keys)+2)
+		for _, key := range keys {
+			field := configField{Key: key, Value: valueOrDash(maskSensitiveValue(key, edited[key]))}
+			if edited[key] != config[key] {
+				field.Changed = "*"
+			}
+			fields = append(fields, field)
+		}
+		fields = append(fields, configField{Key: save}, configField{Key: discard})
+
+		prompt := promptui.Select{
+			Label: "Select a key to change",
+			Items: fields,
+			Templates: &promptui.SelectTemplates{
+				Active:   `▸ {{ .Key | cyan }} {{ .Value | faint }}{{ .Changed | yellow }}`,
+				Inactive: `  {{ .Key }} {{ .Value | faint }}{{ .Changed | yellow }}`,
+				Selected: `{{ .Key }}`,
+			},
+			Size:      15,
+			CursorPos: cursor,
+			Searcher: func(input string, index int) bool {
+				return fuzzyMatch(input, fields[index].Key)
+			},
+		}
+		i, _, err := prompt.Run()
+		if err != nil {
+			return nil, nil
+		}
+		cursor = i
+
+		switch fields[i].Key {
+		case save:
+			return edited, nil
+		case discard:
+			return nil, nil
+		}
+
+		key := fields[i].Key
+		input := promptui.Prompt{
+			Label: key + " (empty to unset)",
+			Validate: func(value string) error {
+				if value == "" {
+					return nil
+				}
+				return validateConfigValue(project, key, value)
+			},
+		}
+		if isSensitiveKey(key) {
+			input.Mask = '*'
+		} else {
+			input.Default = edited[key]
+			input.AllowEdit = true
+		}
+		value, err := input.Run()
+		if err != nil {
+			continue
+		}
+		if value == "" {
+			delete(edited, key)
+		} else {
+			edited[key] = value
+		}
+	}
+}
+
+// editConfigFile opens a copy of the config in $EDITOR and returns the
+// edited values once they pass the checks of apito config set
+func editConfigFile(project string, config map[string]string) (map[string]string, error) {
+	editor := os.Getenv("EDITOR")
+	if editor == "" {
+		editor = "vi"
+	}
+
+	content, err := godotenv.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.CreateTemp("", "apito-config-*.env")
+	if err != nil {
+		return nil, fmt.Errorf("error creating temporary file: %w", err)
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(content + "\n")
+	f.Close()
+	if err != nil {
+		return nil, fmt.Errorf("error writing temporary file: %w", err)
+	}
+
+	for {
+		// $EDITOR may carry arguments, e.g. "code --wait"
+		cmd := exec.Command("sh", "-c", editor+` "$1"`, "sh", f.Name())
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+		if err := cmd.Run(); err != nil {
+			return nil, fmt.Errorf("error running %s: %w", editor, err)
+		}
+
+		edited, err := godotenv.Read(f.Name())
+		if err == nil {
+			err = validateConfig(project, edited)
+		}
+		if err == nil {
+			return edited, nil
+		}
+		logError("Error:", err)
+		if !confirm("Edit again") {
+			return nil, nil
+		}
+	}
+}
+
+// validateConfig checks every value of an edited config, unknown keys of
+// the cli config are kept with a warning
+func validateConfig(project string, config map[string]string) error {
+	for _, key := range sortedKeys(config) {
+		if project == "" {
+			if _, ok := globalConfigKeys[key]; !ok {
+				logWarn("Unknown key", key, "in the cli config")
+			}
+		}
+		if err := validateConfigValue(project, key, config[key]); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,370 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// globalConfigDefaults are used for keys of ~/.apito/.env that are not set
+var globalConfigDefaults = map[string]string{
+	"TELEMETRY":        "disabled",
+	"TIMEOUT":          DefaultRequestTimeout.String(),
+	"DOWNLOAD_TIMEOUT": "0s",
+	"UPDATE_CHECK":     "true",
+	"UPDATE_CHECK_TTL": DefaultUpdateCheckTTL.String(),
+	"CONFIRM_LEVEL":    DefaultConfirmLevel,
+}
+
+// configEnvOverrides are environment variables that take precedence over the
+// configs
+var configEnvOverrides = []string{"APITO_PROJECT", "GITHUB_TOKEN"}
+
+func init() {
+	configListCmd.Flags().String("format", "table", "Output format: table, env, yaml or json")
+	configGetCmd.ValidArgsFunction = completeConfigKeys
+	configSetCmd.ValidArgsFunction = completeConfigKeys
+	configUnsetCmd.ValidArgsFunction = completeConfigKeys
+
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configUnsetCmd)
+	configCmd.AddCommand(configListCmd)
+}
+
+const configKeyHelp = `Keys of the cli config are written as in ~/.apito/.env or in lower case with
+dots, e.g. TIMEOUT, timeout or update.check for UPDATE_CHECK. Keys of a project
+config start with projects.<project>, e.g. projects.blog.engine_url.`
+
+var configGetCmd = &cobra.Command{
+	Use:         "get <key>",
+	Short:       "Print a config value",
+	Long:        "Print the value of a config key, or its default when it is not set. Secrets are masked unless --show-secrets is given.\n\n" + configKeyHelp,
+	Annotations: map[string]string{explicitProjectAnnotation: "true"},
+	Args:        cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		project, key, err := parseConfigKey(args[0])
+		if err != nil {
+			logError("Error:", err)
+			return
+		}
+		config, err := readConfigScope(project)
+		if err != nil {
+			logError("Error reading config:", err)
+			return
+		}
+		value, ok := config[key]
+		if !ok && project == "" {
+			value, ok = globalConfigDefaults[key]
+		}
+		if !ok {
+			logError(fmt.Sprintf("Error: %s is not set", args[0]))
+			return
+		}
+		fmt.Println(maskSensitiveValue(key, value))
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:         "set <key> <value>",
+	Short:       "Set a config value",
+	Long:        "Set a config key after checking its value.\n\n" + configKeyHelp,
+	Annotations: map[string]string{lockAnnotation: "true", explicitProjectAnnotation: "true"},
+	Args:        cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		project, key, err := parseConfigKey(args[0])
+		if err != nil {
+			logError("Error:", err)
+			return
+		}
+		value := args[1]
+		if err := validateConfigValue(project, key, value); err != nil {
+			logError(fmt.Sprintf("Error: %s: %v", args[0], err))
+			return
+		}
+
+		dir, err := getConfigDir(project)
+		if err != nil {
+			logError("Error finding home directory:", err)
+			return
+		}
+		if project == "" {
+			err = updateGlobalConfig(key, value)
+		} else {
+			err = updateConfig(dir, key, value)
+		}
+		if err != nil {
+			logError("Error saving config:", err)
+			return
+		}
+		if !dryRun {
+			fmt.Printf("%s=%s\n", key, maskSensitiveValue(key, value))
+		}
+	},
+}
+
+var configUnsetCmd = &cobra.Command{
+	Use:         "unset <key>",
+	Short:       "Remove a config value",
+	Long:        "Remove a config key, so its default is used again.\n\n" + configKeyHelp,
+	Annotations: map[string]string{lockAnnotation: "true", explicitProjectAnnotation: "true"},
+	Args:        cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		project, key, err := parseConfigKey(args[0])
+		if err != nil {
+			logError("Error:", err)
+			return
+		}
+		dir, err := getConfigDir(project)
+		if err != nil {
+			logError("Error finding home directory:", err)
+			return
+		}
+		config, err := readConfigScope(project)
+		if err != nil {
+			logError("Error reading config:", err)
+			return
+		}
+		if _, ok := config[key]; !ok {
+			fmt.Println(args[0], "is not set")
+			return
+		}
+		delete(config, key)
+		if err := saveConfig(dir, config); err != nil {
+			logError("Error saving config:", err)
+			return
+		}
+		if !dryRun {
+			fmt.Println("Removed", key)
+		}
+	},
+}
+
+var configListCmd = &cobra.Command{
+	Use:         "list",
+	Short:       "Print the effective configuration",
+	Long:        `Print the cli config with the defaults of unset keys, every project config, or only the one of --project, and the environment variables that override them. Secrets are masked unless --show-secrets is given.`,
+	Annotations: map[string]string{explicitProjectAnnotation: "true"},
+	Run: func(cmd *cobra.Command, args []string) {
+		project, _ := cmd.Flags().GetString("project")
+		format, _ := cmd.Flags().GetString("format")
+
+		effective, err := getEffectiveConfig(project)
+		if err != nil {
+			logError("Error reading config:", err)
+			return
+		}
+
+		switch format {
+		case "table":
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+			fmt.Fprintln(w, "KEY\tVALUE\tSOURCE")
+			for _, v := range effective.values() {
+				fmt.Fprintf(w, "%s\t%s\t%s\n", v.Key, valueOrDash(v.Value), v.Source)
+			}
+			w.Flush()
+		case "env":
+			for _, v := range effective.values() {
+				fmt.Printf("%s=%s\n", v.Key, v.Value)
+			}
+		case "yaml":
+			out, err := yaml.Marshal(effective)
+			if err != nil {
+				logError("Error encoding config:", err)
+				return
+			}
+			fmt.Print(string(out))
+		case "json":
+			out, err := json.MarshalIndent(effective, "", "  ")
+			if err != nil {
+				logError("Error encoding config:", err)
+				return
+			}
+			fmt.Println(string(out))
+		default:
+			logError("Error: --format must be table, env, yaml or json")
+		}
+	},
+}
+
+// effectiveConfig is the configuration the cli works with, secrets masked
+type effectiveConfig struct {
+	Global      map[string]string            `json:"global" yaml:"global"`
+	Projects    map[string]map[string]string `json:"projects,omitempty" yaml:"projects,omitempty"`
+	Environment map[string]string            `json:"environment,omitempty" yaml:"environment,omitempty"`
+
+	sources map[string]string
+}
+
+type configValue struct {
+	Key, Value, Source string
+}
+
+// values returns every value as a dot-path key, sorted within each scope
+func (c effectiveConfig) values() []configValue {
+	var values []configValue
+	for _, key := range sortedKeys(c.Global) {
+		values = append(values, configValue{formatConfigKey("", key), c.Global[key], c.sources[key]})
+	}
+	for _, project := range sortedKeys(c.Projects) {
+		for _, key := range sortedKeys(c.Projects[project]) {
+			values = append(values, configValue{formatConfigKey(project, key), c.Projects[project][key], filepath.Join("~/.apito", project, ConfigFile)})
+		}
+	}
+	for _, key := range sortedKeys(c.Environment) {
+		values = append(values, configValue{key, c.Environment[key], "environment"})
+	}
+	return values
+}
+
+// getEffectiveConfig reads the cli config with defaults, the project configs
+// and the environment overrides
+func getEffectiveConfig(project string) (effectiveConfig, error) {
+	effective := effectiveConfig{
+		Global:      map[string]string{},
+		Projects:    map[string]map[string]string{},
+		Environment: map[string]string{},
+		sources:     map[string]string{},
+	}
+
+	global, err := getGlobalConfig()
+	if err != nil {
+		return effective, err
+	}
+	for key, value := range globalConfigDefaults {
+		effective.Global[key] = value
+		effective.sources[key] = "default"
+	}
+	for key, value := range global {
+		effective.Global[key] = maskSensitiveValue(key, value)
+		effective.sources[key] = "~/.apito/" + ConfigFile
+	}
+
+	apitoDir, err := getApitoDir()
+	if err != nil {
+		return effective, err
+	}
+	projects := []string{project}
+	if project == "" {
+		projects = listProjectNames(apitoDir)
+	}
+	for _, p := range projects {
+		config, err := getConfig(filepath.Join(apitoDir, p))
+		if err != nil {
+			return effective, fmt.Errorf("project %s: %w", p, err)
+		}
+		masked := map[string]string{}
+		for key, value := range config {
+			masked[key] = maskSensitiveValue(key, value)
+		}
+		effective.Projects[p] = masked
+	}
+
+	for _, name := range configEnvOverrides {
+		if value, ok := os.LookupEnv(name); ok {
+			effective.Environment[name] = maskSensitiveValue(name, value)
+		}
+	}
+	return effective, nil
+}
+
+// parseConfigKey splits a dot-path key into the project, empty for the cli
+// config, and the key as written in the .env file
+func parseConfigKey(path string) (string, string, error) {
+	parts := strings.Split(path, ".")
+	project := ""
+	switch {
+	case len(parts) >= 3 && strings.EqualFold(parts[0], "projects"):
+		project, parts = parts[1], parts[2:]
+	case len(parts) >= 2 && strings.EqualFold(parts[0], "global"):
+		parts = parts[1:]
+	}
+	for _, p := range parts {
+		if p == "" {
+			return "", "", fmt.Errorf("invalid key %q", path)
+		}
+	}
+	key := strings.ToUpper(strings.Join(parts, "_"))
+
+	if project == "" {
+		if _, ok := globalConfigKeys[key]; !ok {
+			return "", "", fmt.Errorf("unknown key %s, the cli config keys are %s, project keys start with projects.<project>.", path, strings.Join(sortedKeys(globalConfigKeys), ", "))
+		}
+		return "", key, nil
+	}
+	if err := validateProjectExists(project); err != nil {
+		return "", "", err
+	}
+	return project, key, nil
+}
+
+// formatConfigKey is the dot-path form of a key, the reverse of parseConfigKey
+func formatConfigKey(project, key string) string {
+	key = strings.ToLower(key)
+	if project == "" {
+		return key
+	}
+	return "projects." + project + "." + key
+}
+
+// validateConfigValue checks a value before it is written to a config
+func validateConfigValue(project, key, value string) error {
+	if project == "" {
+		if validate := globalConfigKeys[key]; validate != nil {
+			return validate(value)
+		}
+		return nil
+	}
+	if strings.HasSuffix(key, "_URL") {
+		return validateURL(value)
+	}
+	return nil
+}
+
+// readConfigScope reads the project config, or the cli config without a
+// project
+func readConfigScope(project string) (map[string]string, error) {
+	if project == "" {
+		return getGlobalConfig()
+	}
+	dir, err := getConfigDir(project)
+	if err != nil {
+		return nil, err
+	}
+	return getConfig(dir)
+}
+
+func completeConfigKeys(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var keys []string
+	for _, key := range sortedKeys(globalConfigKeys) {
+		keys = append(keys, formatConfigKey("", key))
+	}
+	if apitoDir, err := getApitoDir(); err == nil {
+		for _, project := range listProjectNames(apitoDir) {
+			config, _ := getConfig(filepath.Join(apitoDir, project))
+			for _, key := range sortedKeys(config) {
+				keys = append(keys, formatConfigKey(project, key))
+			}
+		}
+	}
+	return keys, cobra.ShellCompDirectiveNoFileComp
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/mholt/archiver/v3"
+)
+
+// ConsoleDir in ~/.apito holds the static files of the console, it is shared
+// by all projects
+const ConsoleDir = "console"
+
+// ConsoleAsset is the release asset of the console, the same for every OS
+const ConsoleAsset = "console.zip"
+
+// downloadAndExtractConsole installs the console release into
+// ~/.apito/console. The release is extracted next to it first and swapped in
+// only when complete, so a failed update keeps the installed console.
+func downloadAndExtractConsole(releaseTag string) error {
+	apitoDir, err := getApitoDir()
+	if err != nil {
+		return err
+	}
+	consoleDir := filepath.Join(apitoDir, ConsoleDir)
+	assetURL := releaseDownloadURL(ConsoleRepo, releaseTag, ConsoleAsset)
+	if dryRunSkip("download %s and extract it to %s", assetURL, consoleDir) {
+		return nil
+	}
+
+	if err := os.MkdirAll(apitoDir, 0755); err != nil {
+		return fmt.Errorf("error creating apito directory: %w", err)
+	}
+	tmpDir, err := os.MkdirTemp(apitoDir, ".console-")
+	if err != nil {
+		return fmt.Errorf("error creating temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	logInfo("Downloading console from:", assetURL)
+	filename := filepath.Join(tmpDir, ConsoleAsset)
+	if err := downloadFile(assetURL, filename); err != nil {
+		return err
+	}
+
+	extractDir := filepath.Join(tmpDir, "extracted")
+	if err := archiver.Unarchive(filename, extractDir); err != nil {
+		return fmt.Errorf("error extracting file: %w", err)
+	}
+	root, err := archiveRoot(extractDir)
+	if err != nil {
+		return err
+	}
+
+	oldDir := consoleDir + ".old"
+	os.RemoveAll(oldDir)
+	if _, err := os.Stat(consoleDir); err == nil {
+		if err := os.Rename(consoleDir, oldDir); err != nil {
+			return fmt.Errorf("error replacing console: %w", err)
+		}
+	}
+	if err := os.Rename(root, consoleDir); err != nil {
+		os.Rename(oldDir, consoleDir)
+		return fmt.Errorf("error replacing console: %w", err)
+	}
+	os.RemoveAll(oldDir)
+
+	logInfo("Console extracted to:", consoleDir)
+	return updateGlobalConfig("CONSOLE_VERSION", releaseTag)
+}
+
+// archiveRoot returns the directory holding the extracted files, which is
+// the single top-level folder when the archive has one
+func archiveRoot(dir string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", fmt.Errorf("error reading extracted files: %w", err)
+	}
+	if len(entries) == 0 {
+		return "", fmt.Errorf("the archive is empty")
+	}
+	if len(entries) == 1 && entries[0].IsDir() {
+		return filepath.Join(dir, entries[0].Name()), nil
+	}
+	return dir, nil
+}
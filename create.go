@@ -1,8 +1,11 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
+	"path"
 	"path/filepath"
 	"runtime"
 	"strings"
@@ -19,29 +22,67 @@ func init() {
 	createCmd.Flags().StringP("function", "f", "", "Adds a function for that project")
 	createCmd.Flags().StringP("model", "m", "", "Creates a model in the project")
 	createCmd.Flags().StringP("name", "n", "", "Name of the function or model or project")
+	createCmd.Flags().String("full-name", "", "Project full name (skips the prompt)")
+	createCmd.Flags().String("system-db", "", "System database engine: postgres, mysql, mariadb or storageDb (skips the prompt)")
+	createCmd.Flags().String("project-db", "", "Project database engine: postgres, mysql, mariadb or firestore (skips the prompt)")
+	createCmd.Flags().String("from-file", "", "Create the project from a YAML setup file without any prompts")
+	createCmd.Flags().StringArray("set", nil, "Preset a config value as KEY=VALUE, e.g. SYSTEM_DB_HOST=localhost (repeatable)")
 }
 
+var systemDBEngines = []string{"postgres", "mysql", "mariadb", "storageDb"}
+var projectDBEngines = []string{"postgres", "mysql", "mariadb", "firestore"}
+
 var createCmd = &cobra.Command{
-	Use:       "create",
-	Short:     "Create a new project, function, or model",
-	Long:      `Create a new project, function, or model with the specified parameters.`,
-	ValidArgs: []string{"project", "function", "model"},
-	Args:      cobra.MatchAll(cobra.MinimumNArgs(1), cobra.OnlyValidArgs),
+	Annotations: mutatingAnnotations,
+	Use:         "create",
+	Short:       "Create a new project, function, or model",
+	Long:        `Create a new project, function, or model with the specified parameters.`,
+	ValidArgs:   []string{"project", "function", "model"},
+	Args:        cobra.MatchAll(cobra.MinimumNArgs(1), cobra.OnlyValidArgs),
 	Run: func(cmd *cobra.Command, args []string) {
 
 		actionName := args[0] // take only one and should be one
 
 		projectName, _ := cmd.Flags().GetString("name")
+		fromFile, _ := cmd.Flags().GetString("from-file")
+
+		preset := map[string]string{}
+		if actionName == "project" && fromFile != "" {
+			name, filePreset, err := readSetupFile(fromFile)
+			if err != nil {
+				logError("Error:", err)
+				return
+			}
+			if projectName == "" {
+				projectName = name
+			}
+			preset = filePreset
+			nonInteractive = true
+		}
+
 		if projectName == "" {
-			fmt.Println("Error: project name is required")
+			logError("Error: project name is required")
 			return
 		}
 
 		projectName = strings.TrimSpace(projectName)
+		if err := validatePathName(projectName); err != nil {
+			logError("Error: invalid project name:", err)
+			return
+		}
 
 		switch actionName {
 		case "project":
-			createProject(projectName)
+			flagPreset, err := getProjectPreset(cmd)
+			if err != nil {
+				logError("Error:", err)
+				return
+			}
+			// flags take precedence over the setup file
+			for k, v := range flagPreset {
+				preset[k] = v
+			}
+			createProject(projectName, preset)
 		case "function":
 			functionName, _ := cmd.Flags().GetString(actionName)
 			createFunction(projectName, functionName)
@@ -49,39 +90,61 @@ var createCmd = &cobra.Command{
 			modelName, _ := cmd.Flags().GetString(actionName)
 			createModel(projectName, modelName)
 		default:
-			fmt.Println("Invalid create option. Use 'project', 'function', or 'model'.")
+			logError("Invalid create option. Use 'project', 'function', or 'model'.")
 		}
 	},
 }
 
-func createProject(project string) {
+// getProjectPreset collects the config values given as flags, any value
+// present in the preset is used as is instead of prompting for it
+func getProjectPreset(cmd *cobra.Command) (map[string]string, error) {
+	values, _ := cmd.Flags().GetStringArray("set")
+	preset, err := parseKeyValues(values)
+	if err != nil {
+		return nil, err
+	}
+
+	if fullName, _ := cmd.Flags().GetString("full-name"); fullName != "" {
+		preset["PROJECT_NAME"] = fullName
+	}
+	if db, _ := cmd.Flags().GetString("system-db"); db != "" {
+		preset["SYSTEM_DB_ENGINE"] = db
+	}
+	if db, _ := cmd.Flags().GetString("project-db"); db != "" {
+		preset["PROJECT_DB_ENGINE"] = db
+	}
+
+	return preset, nil
+}
+
+func createProject(project string, preset map[string]string) {
 
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
-		fmt.Println("Error finding home directory:", err)
+		logError("Error finding home directory:", err)
 		return
 	}
 	projectDir := filepath.Join(homeDir, ".apito", project)
 
 	if _, err = os.Stat(projectDir); err == nil {
-		// Create the database file
-		fmt.Println(Red + fmt.Sprintf("A project with the name %s already exists in %s\nPlesea Choose a different name", project, projectDir) + Reset)
-		return
-	}
-
-	if err := os.MkdirAll(projectDir, 0755); err != nil {
-		fmt.Println("Error creating project directory:", err)
+		repairProject(project, projectDir, preset)
 		return
 	}
 
 	// Prompt for project description
-	prompt := promptui.Prompt{
-		Label: "Project Full Name",
+	projectFullName := preset["PROJECT_NAME"]
+	if projectFullName == "" && nonInteractive {
+		projectFullName = project
 	}
-	projectFullName, err := prompt.Run()
-	if err != nil {
-		fmt.Println("Prompt failed:", err)
-		return
+	if projectFullName == "" {
+		prompt := promptui.Prompt{
+			Label: "Project Full Name",
+		}
+		projectFullName, err = prompt.Run()
+		if err != nil {
+			logError("Prompt failed:", err)
+			return
+		}
 	}
 
 	/*
@@ -95,19 +158,26 @@ func createProject(project string) {
 		fmt.Println(style.Render(fmt.Sprintf("Project, %s", projectFullName)))
 	*/
 
-	fmt.Println(Blue + fmt.Sprintf(`Project '%s' needs a System database which will be used to store your login details, project schema information,`, projectFullName) + Reset)
-	fmt.Println(Blue + `cloud functions, secret keys and many more system related information. Please Choose a type of system database.` + Reset)
-	fmt.Println(`To get started quickly choose 'storageDb' which is a BadgerDB powered database.`)
-	fmt.Println(Yellow + `Note : storageDB is not recommended for production use.` + Reset)
+	db := preset["SYSTEM_DB_ENGINE"]
+	if db == "" && nonInteractive {
+		logError("Error: the system database engine is required")
+		return
+	}
+	if db == "" {
+		fmt.Println(Blue + fmt.Sprintf(`Project '%s' needs a System database which will be used to store your login details, project schema information,`, projectFullName) + Reset)
+		fmt.Println(Blue + `cloud functions, secret keys and many more system related information. Please Choose a type of system database.` + Reset)
+		fmt.Println(`To get started quickly choose 'storageDb' which is a BadgerDB powered database.`)
+		fmt.Println(Yellow + `Note : storageDB is not recommended for production use.` + Reset)
+	}
+
+	if db == "badger" {
+		db = "storageDb"
+	}
 
 	// Prompt for database selection
-	dbPrompt := promptui.Select{
-		Label: emoji.Sprint(":electric_plug: Select Apito System Database"),
-		Items: []string{"postgres", "mysql", "storageDb"},
-	}
-	_, db, err := dbPrompt.Run()
+	db, err = selectOption(emoji.Sprint(":electric_plug: Select Apito System Database"), systemDBEngines, db)
 	if err != nil {
-		fmt.Println("Prompt failed:", err)
+		logError("Error:", err)
 		return
 	}
 
@@ -121,15 +191,16 @@ func createProject(project string) {
 		"PROJECT_ID":       project,
 		"PROJECT_NAME":     projectFullName,
 		"SYSTEM_DB_ENGINE": db,
+		"CREATED_AT":       time.Now().UTC().Format(time.RFC3339),
 	}
 
 	switch db {
-	case "storageDb":
-		fmt.Println(Green + fmt.Sprintf(`A local database has been created in %s/db`, projectDir) + Reset)
-	case "postgresql", "mysql", "mariadb":
-		dbConfigs := getDBConfig("SYSTEM")
+	case "badger":
+		fmt.Println(Green + fmt.Sprintf(`A local database will be created in %s/db`, projectDir) + Reset)
+	case "postgres", "mysql", "mariadb":
+		dbConfigs := getDBConfig("SYSTEM", db, preset)
 		if dbConfigs == nil {
-			fmt.Println("Error getting database configuration")
+			logError("Error getting database configuration")
 			return
 		}
 		for k, v := range dbConfigs {
@@ -137,17 +208,20 @@ func createProject(project string) {
 		}
 	}
 
-	fmt.Println(Blue + emoji.Sprint("Project Database is the main database of your project") + Reset)
-	fmt.Println(Yellow + `Note : firestore/firebase support is still in alpha. Check progess of the driver here: https://github.com/orgs/apito-io/projects/5` + Reset)
+	db = preset["PROJECT_DB_ENGINE"]
+	if db == "" && nonInteractive {
+		logError("Error: the project database engine is required")
+		return
+	}
+	if db == "" {
+		fmt.Println(Blue + emoji.Sprint("Project Database is the main database of your project") + Reset)
+		fmt.Println(Yellow + `Note : firestore/firebase support is still in alpha. Check progess of the driver here: https://github.com/orgs/apito-io/projects/5` + Reset)
+	}
 
 	// Prompt for database selection
-	dbPrompt = promptui.Select{
-		Label: emoji.Sprint(":rocket: Choose Apito Project Database"),
-		Items: []string{"postgres", "mysql", "mariadb", "firestore"},
-	}
-	_, db, err = dbPrompt.Run()
+	db, err = selectOption(emoji.Sprint(":rocket: Choose Apito Project Database"), projectDBEngines, db)
 	if err != nil {
-		fmt.Println("Prompt failed:", err)
+		logError("Error:", err)
 		return
 	}
 
@@ -156,10 +230,10 @@ func createProject(project string) {
 	switch db {
 	case "firestore":
 		fmt.Println(Red + `Support for Firestore is still in alpha. Check progess of the driver here: https://github.com/orgs/apito-io/projects/5` + Reset)
-	case "postgres", "mysql":
-		dbConfigs := getDBConfig("PROJECT")
+	case "postgres", "mysql", "mariadb":
+		dbConfigs := getDBConfig("PROJECT", db, preset)
 		if dbConfigs == nil {
-			fmt.Println("Error getting database configuration")
+			logError("Error getting database configuration")
 			return
 		}
 		for k, v := range dbConfigs {
@@ -167,21 +241,41 @@ func createProject(project string) {
 		}
 	}
 
+	// any other preset value is passed through to the engine config as is
+	for k, v := range preset {
+		if _, ok := config[k]; !ok {
+			config[k] = v
+		}
+	}
+
+	if !dryRunSkip("create %s", projectDir) {
+		if err := os.MkdirAll(projectDir, 0755); err != nil {
+			logError("Error creating project directory:", err)
+			return
+		}
+	}
+
 	if err := saveConfig(projectDir, config); err != nil {
-		fmt.Println("Error saving config file:", err)
+		logError("Error saving config file:", err)
 		return
 	}
 
-	// Get the latest release tag from GitHub API
-	releaseTag, err := getLatestReleaseTag()
-	if err != nil {
-		fmt.Println("error fetching latest release tag: %w", err)
-		return
+	// Get the latest release tag from GitHub API unless a version is pinned
+	releaseTag := preset["ENGINE_VERSION"]
+	if releaseTag == "" {
+		releaseTag, err = getLatestReleaseTag()
+		if err != nil {
+			logError("Error fetching latest release tag:", err)
+			return
+		}
 	}
 
 	// Detect runtime environment and download the appropriate asset
 	if err := downloadAndExtractEngine(project, releaseTag, projectDir); err != nil {
-		fmt.Println("Error downloading and extracting binary:", err)
+		logError("Error downloading and extracting binary:", err)
+		return
+	}
+	if dryRun {
 		return
 	}
 
@@ -190,75 +284,238 @@ func createProject(project string) {
 	fmt.Println(Green + fmt.Sprintf(`> apito run -p %s`, project) + Reset)
 }
 
-func getDBConfig(_prefix string) map[string]string {
-	prompt := promptui.Prompt{Label: "Database Host"}
-	dbHost, err := prompt.Run()
+// selectOption returns the preset value when it is one of the items,
+// otherwise it prompts the user to pick one
+func selectOption(label string, items []string, preset string) (string, error) {
+	if preset != "" {
+		if !ArrayContains(items, preset) {
+			return "", fmt.Errorf("invalid value %q, must be one of %s", preset, strings.Join(items, ", "))
+		}
+		return preset, nil
+	}
+
+	prompt := promptui.Select{
+		Label: label,
+		Items: items,
+	}
+	_, value, err := prompt.Run()
 	if err != nil {
-		fmt.Println("Prompt failed:", err)
-		return nil
+		return "", fmt.Errorf("prompt failed: %w", err)
 	}
 
-	config := map[string]string{
-		_prefix + "_DB_HOST": dbHost,
+	return value, nil
+}
+
+// getDBConfig asks for the connection details of a postgres, mysql or
+// mariadb database and checks them right away, on failure the details are
+// asked again with the previous answers as defaults
+func getDBConfig(_prefix, engine string, preset map[string]string) map[string]string {
+	fields := []struct {
+		key   string
+		label string
+		mask  rune
+	}{
+		{"HOST", "Database Host", 0},
+		{"PORT", "Database Port", 0},
+		{"USER", "Database User", 0},
+		{"PASS", "Database Password", '*'},
+		{"NAME", "Database Name", 0},
+	}
+
+	config := map[string]string{}
+	retry := false
+	for {
+		for _, field := range fields {
+			key := _prefix + "_DB_" + field.key
+			if value, ok := preset[key]; ok && !retry {
+				config[key] = value
+				continue
+			}
+
+			if nonInteractive {
+				if field.key == "PASS" {
+					config[key] = ""
+					continue
+				}
+				logError("Error:", key, "is required")
+				return nil
+			}
+
+			prompt := promptui.Prompt{Label: field.label, Mask: field.mask, Default: config[key], AllowEdit: field.mask == 0}
+			value, err := prompt.Run()
+			if err != nil {
+				logError("Prompt failed:", err)
+				return nil
+			}
+			config[key] = value
+		}
+
+		logInfo(fmt.Sprintf("Connecting to %s at %s:%s...", engine, config[_prefix+"_DB_HOST"], config[_prefix+"_DB_PORT"]))
+		err := pingDatabase(engine, _prefix, config)
+		if err == nil {
+			logInfo(Green + "Connected successfully" + Reset)
+			return config
+		}
+
+		logError("Error connecting to database:", err)
+		if nonInteractive {
+			return nil
+		}
+
+		choice := promptui.Select{
+			Label: "The database is not reachable",
+			Items: []string{"Enter the connection details again", "Keep them anyway", "Cancel"},
+		}
+		index, _, err := choice.Run()
+		if err != nil || index == 2 {
+			return nil
+		}
+		if index == 1 {
+			return config
+		}
+		retry = true
 	}
+}
+
+func downloadAndExtractEngine(projectName, releaseTag string, destDir string) error {
 
-	prompt = promptui.Prompt{Label: "Database Port"}
-	dbPort, err := prompt.Run()
+	assetURL, err := getEngineAssetURL(releaseTag)
 	if err != nil {
-		fmt.Println("Prompt failed:", err)
+		return err
+	}
+
+	if dryRunSkip("download %s and extract it to %s", assetURL, destDir) {
 		return nil
 	}
-	config[_prefix+"_DB_PORT"] = dbPort
+	logInfo("Downloading engine from:", assetURL)
 
-	prompt = promptui.Prompt{Label: "Database User"}
-	dbUser, err := prompt.Run()
+	// the release is part of the file name so an interrupted download is
+	// only resumed for the same release
+	asset := path.Base(assetURL)
+	filename := filepath.Join(destDir, strings.TrimSuffix(asset, ".zip")+"-"+releaseTag+".zip")
+	if err := downloadFile(assetURL, filename); err != nil {
+		return err
+	}
+
+	// Unzip the file
+	err = archiver.Unarchive(filename, destDir)
 	if err != nil {
-		fmt.Println("Prompt failed:", err)
-		return nil
+		return fmt.Errorf("error extracting file: %w", err)
+	}
+	// only an interrupted download is kept, to be resumed next time
+	if err := os.Remove(filename); err != nil {
+		logWarn("Error removing engine archive:", err)
 	}
-	config[_prefix+"_DB_USER"] = dbUser
 
-	prompt = promptui.Prompt{Label: "Database Password", Mask: '*'}
-	dbPass, err := prompt.Run()
+	// Rename the binary to "engine"
+	binaryName := "engine"
+	if runtime.GOOS == "windows" {
+		binaryName += ".exe"
+	}
+	err = os.Rename(filepath.Join(destDir, binaryName), filepath.Join(destDir, projectName))
 	if err != nil {
-		fmt.Println("Prompt failed:", err)
-		return nil
+		return fmt.Errorf("error renaming binary: %w", err)
+	}
+
+	logInfo("Engine binary extracted to:", filepath.Join(destDir, projectName))
+	return updateConfig(destDir, "ENGINE_VERSION", releaseTag)
+}
+
+// getEngineAssetURL returns the engine release asset for this machine. When
+// a release has no arm64 build for macOS the amd64 build is used through
+// Rosetta 2.
+func getEngineAssetURL(releaseTag string) (string, error) {
+	switch runtime.GOOS {
+	case "linux", "darwin", "windows":
+	default:
+		return "", fmt.Errorf("unsupported OS: %s", runtime.GOOS)
+	}
+
+	baseURL := releaseDownloadURL(EngineRepo, releaseTag, "")
+	assetURL := baseURL + fmt.Sprintf("engine-%s-%s.zip", runtime.GOOS, runtime.GOARCH)
+	if runtime.GOARCH == "amd64" {
+		return assetURL, nil
 	}
-	config[_prefix+"_DB_PASS"] = dbPass
 
-	prompt = promptui.Prompt{Label: "Database Name"}
-	dbName, err := prompt.Run()
+	exists, err := urlExists(assetURL)
 	if err != nil {
-		fmt.Println("Prompt failed:", err)
-		return nil
+		return "", err
+	}
+	if exists {
+		return assetURL, nil
 	}
-	config[_prefix+"_DB_NAME"] = dbName
 
-	return config
+	if runtime.GOOS == "darwin" {
+		logWarn(fmt.Sprintf("Engine %s has no build for darwin/%s, using the amd64 build which runs under Rosetta 2 and is slower", releaseTag, runtime.GOARCH))
+		return baseURL + "engine-darwin-amd64.zip", nil
+	}
+	return "", fmt.Errorf("engine %s has no build for %s/%s, use another engine version", releaseTag, runtime.GOOS, runtime.GOARCH)
 }
 
-func downloadAndExtractEngine(projectName, releaseTag string, destDir string) error {
+// urlExists reports whether a HEAD request to url succeeds
+func urlExists(url string) (bool, error) {
+	resp, err := newHTTPClient(getRequestTimeout()).Head(url)
+	if err != nil {
+		return false, fmt.Errorf("error checking %s: %w", url, err)
+	}
+	resp.Body.Close()
 
-	baseURL := fmt.Sprintf("https://github.com/apito-io/engine/releases/download/%s/", releaseTag)
-	var assetURL string
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return false, nil
+	case resp.StatusCode >= 400:
+		return false, fmt.Errorf("error checking %s: status code %d", url, resp.StatusCode)
+	}
+	return true, nil
+}
 
-	switch runtime.GOOS {
-	case "linux":
-		assetURL = baseURL + "engine-linux-amd64.zip"
-	case "darwin":
-		assetURL = baseURL + "engine-darwin-amd64.zip"
-	case "windows":
-		assetURL = baseURL + "engine-windows-amd64.zip"
-	default:
-		return fmt.Errorf("unsupported OS: %s", runtime.GOOS)
+const downloadAttempts = 3
+
+// releaseDownloadURL returns the URL of a release asset on github.com, or on
+// DOWNLOAD_MIRROR from ~/.apito/.env which must use the same layout
+func releaseDownloadURL(repo, tag, asset string) string {
+	base := "https://github.com"
+	if config, err := getGlobalConfig(); err == nil && config["DOWNLOAD_MIRROR"] != "" {
+		base = strings.TrimRight(config["DOWNLOAD_MIRROR"], "/")
 	}
+	return fmt.Sprintf("%s/%s/releases/download/%s/%s", base, repo, tag, asset)
+}
 
-	fmt.Println("Downloading engine from:", assetURL)
+// downloadFile downloads url to filename with progress output. A partial
+// file left by an earlier attempt is resumed, failed attempts are retried
+// and the size of the result is checked against the size the server sent.
+func downloadFile(url, filename string) error {
+	var err error
+	for attempt := 1; attempt <= downloadAttempts; attempt++ {
+		if attempt > 1 {
+			wait := time.Duration(attempt-1) * 2 * time.Second
+			logWarn(fmt.Sprintf("Download failed: %v, retrying in %s (%d/%d)", err, wait, attempt, downloadAttempts))
+			time.Sleep(wait)
+		}
+		err = downloadFileOnce(url, filename)
+		if err == nil {
+			return nil
+		}
+		// a missing asset or denied access does not go away by retrying
+		var status grab.StatusCodeError
+		if errors.As(err, &status) && status < 500 && status != http.StatusTooManyRequests {
+			break
+		}
+	}
+	return fmt.Errorf("error downloading %s: %w", url, err)
+}
 
-	// Download the file
-	resp, err := grab.Get(destDir, assetURL)
+func downloadFileOnce(url, filename string) error {
+	req, err := grab.NewRequest(filename, url)
 	if err != nil {
-		return fmt.Errorf("error downloading file: %w", err)
+		return err
+	}
+
+	client := grab.NewClient()
+	client.HTTPClient = newHTTPClient(getDownloadTimeout())
+	resp := client.Do(req)
+	if resp.DidResume {
+		logInfo(fmt.Sprintf("  resuming at %v bytes", resp.BytesComplete()))
 	}
 
 	// start UI loop
@@ -269,10 +526,10 @@ Loop:
 	for {
 		select {
 		case <-t.C:
-			fmt.Printf("  transferred %v / %v bytes (%.2f%%)\n",
+			logInfo(fmt.Sprintf("  transferred %v / %v bytes (%.2f%%)",
 				resp.BytesComplete(),
-				resp.Size,
-				100*resp.Progress())
+				resp.Size(),
+				100*resp.Progress()))
 
 		case <-resp.Done:
 			// download is complete
@@ -282,41 +539,32 @@ Loop:
 
 	// check for errors
 	if err := resp.Err(); err != nil {
-		fmt.Fprintf(os.Stderr, "Download failed: %v\n", err)
 		return err
 	}
 
-	fmt.Println("Downloaded file saved to:", resp.Filename)
-
-	// Unzip the file
-	err = archiver.Unarchive(resp.Filename, destDir)
+	info, err := os.Stat(resp.Filename)
 	if err != nil {
-		return fmt.Errorf("error extracting file: %w", err)
-	}
-
-	// Rename the binary to "engine"
-	binaryName := "engine"
-	if runtime.GOOS == "windows" {
-		binaryName += ".exe"
+		return err
 	}
-	err = os.Rename(filepath.Join(destDir, binaryName), filepath.Join(destDir, projectName))
-	if err != nil {
-		return fmt.Errorf("error renaming binary: %w", err)
+	if resp.Size() > 0 && info.Size() != resp.Size() {
+		// start over on the next attempt instead of resuming a broken file
+		os.Remove(resp.Filename)
+		return fmt.Errorf("downloaded %d bytes, expected %d", info.Size(), resp.Size())
 	}
 
-	fmt.Println("Engine binary extracted to:", filepath.Join(destDir, projectName))
+	logInfo("Downloaded file saved to:", resp.Filename)
 	return nil
 }
 
 func createFunction(project, functionName string) {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
-		fmt.Println("Error finding home directory:", err)
+		logError("Error finding home directory:", err)
 		return
 	}
 	functionDir := filepath.Join(homeDir, ".apito", project, "functions", functionName)
 	if err := os.MkdirAll(functionDir, 0755); err != nil {
-		fmt.Println("Error creating function directory:", err)
+		logError("Error creating function directory:", err)
 		return
 	}
 	fmt.Println("Function created:", functionName)
@@ -0,0 +1,373 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/client"
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/spf13/cobra"
+)
+
+const dbPingTimeout = 5 * time.Second
+
+// sqlDriver returns the database/sql driver and connection string for the
+// <prefix>_DB_* keys of a postgres, mysql or mariadb database
+func sqlDriver(engine, prefix string, config map[string]string) (string, string, error) {
+	host := config[prefix+"_DB_HOST"]
+	port := config[prefix+"_DB_PORT"]
+	user := config[prefix+"_DB_USER"]
+	pass := config[prefix+"_DB_PASS"]
+	name := config[prefix+"_DB_NAME"]
+
+	switch engine {
+	case "postgres":
+		dsn := url.URL{
+			Scheme:   "postgres",
+			User:     url.UserPassword(user, pass),
+			Host:     net.JoinHostPort(host, port),
+			Path:     "/" + name,
+			RawQuery: "sslmode=disable",
+		}
+		return "postgres", dsn.String(), nil
+	case "mysql", "mariadb":
+		dsn := mysql.NewConfig()
+		dsn.User = user
+		dsn.Passwd = pass
+		dsn.Net = "tcp"
+		dsn.Addr = net.JoinHostPort(host, port)
+		dsn.DBName = name
+		return "mysql", dsn.FormatDSN(), nil
+	}
+	return "", "", fmt.Errorf("unsupported database engine: %s", engine)
+}
+
+// openDatabase connects to the database and checks that it answers
+func openDatabase(engine, prefix string, config map[string]string) (*sql.DB, error) {
+	driver, dsn, err := sqlDriver(engine, prefix, config)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbPingTimeout)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// pingDatabase checks that the database accepts the credentials
+func pingDatabase(engine, prefix string, config map[string]string) error {
+	db, err := openDatabase(engine, prefix, config)
+	if err != nil {
+		return err
+	}
+	return db.Close()
+}
+
+// dbImages maps the image names of database containers to the engine
+// written to <prefix>_DB_ENGINE and its default port
+var dbImages = []struct {
+	image  string
+	engine string
+	port   string
+}{
+	{"postgres", "postgres", "5432"},
+	{"postgis", "postgres", "5432"},
+	{"mariadb", "mariadb", "3306"},
+	{"mysql", "mysql", "3306"},
+}
+
+func init() {
+	dbAdoptCmd.Flags().String("container", "", "Name or ID of the database container")
+	dbAdoptCmd.Flags().String("type", "project", "Which database of the project it is: project or system")
+	dbAdoptCmd.MarkFlagRequired("container")
+
+	dbRotatePasswordCmd.Flags().Int("length", DefaultPasswordLength, "Length of the new password")
+
+	dbCmd.AddCommand(dbAdoptCmd)
+	dbCmd.AddCommand(dbRotatePasswordCmd)
+}
+
+var dbCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Manage the databases of a project",
+	Long:  `Manage the external databases a project is connected to.`,
+}
+
+var dbAdoptCmd = &cobra.Command{
+	Use:         "adopt",
+	Short:       "Use an existing database container for a project",
+	Long:        `Inspect a running postgres, mysql or mariadb container and write its engine, published port, user, password and database to the PROJECT_DB_* or SYSTEM_DB_* keys of the project config, instead of entering them in apito create.`,
+	Annotations: mutatingAnnotations,
+	Run: func(cmd *cobra.Command, args []string) {
+		project, _ := cmd.Flags().GetString("project")
+		containerName, _ := cmd.Flags().GetString("container")
+		dbType, _ := cmd.Flags().GetString("type")
+
+		if project == "" {
+			logError("Error: --project is required")
+			return
+		}
+		if dbType != "project" && dbType != "system" {
+			logError("Error: --type must be project or system")
+			return
+		}
+		prefix := strings.ToUpper(dbType)
+
+		apitoDir, err := getApitoDir()
+		if err != nil {
+			logError("Error finding home directory:", err)
+			return
+		}
+		projectDir := filepath.Join(apitoDir, project)
+		config, err := getConfig(projectDir)
+		if err != nil {
+			logError("Error reading project config:", err)
+			return
+		}
+
+		adopted, err := inspectDatabaseContainer(containerName, prefix)
+		if err != nil {
+			logError("Error:", err)
+			return
+		}
+
+		for _, key := range []string{"ENGINE", "HOST", "PORT", "USER", "PASS", "NAME"} {
+			key = prefix + "_DB_" + key
+			fmt.Printf("  %s=%s\n", key, maskSensitiveValue(key, adopted[key]))
+		}
+
+		if config[prefix+"_DB_ENGINE"] != "" && !confirmSensitiveOperation(fmt.Sprintf("Replace the %s database settings of %s", dbType, project), riskDestructive) {
+			return
+		}
+
+		logInfo(fmt.Sprintf("Connecting to %s at %s:%s...", adopted[prefix+"_DB_ENGINE"], adopted[prefix+"_DB_HOST"], adopted[prefix+"_DB_PORT"]))
+		if err := pingDatabase(adopted[prefix+"_DB_ENGINE"], prefix, adopted); err != nil {
+			logWarn("The database does not answer yet:", err)
+		}
+
+		for key, value := range adopted {
+			config[key] = value
+		}
+		if err := saveConfig(projectDir, config); err != nil {
+			logError("Error saving config:", err)
+			return
+		}
+		if dryRun {
+			return
+		}
+		fmt.Println(Green+fmt.Sprintf("The %s database of %s is now the container", dbType, project)+Reset, containerName)
+	},
+}
+
+var dbRotatePasswordCmd = &cobra.Command{
+	Use:         "rotate-password [system|project]",
+	Short:       "Replace the database password of a project with a random one",
+	Long:        `Generate a random password, set it for the database user of the system or project database (default project) and save it to the project config. The old password is only replaced in the config once the database accepted the new one. A running engine keeps the old connection details until it is restarted.`,
+	Annotations: mutatingAnnotations,
+	Args:        cobra.MatchAll(cobra.MaximumNArgs(1), cobra.OnlyValidArgs),
+	ValidArgs:   []string{"system", "project"},
+	Run: func(cmd *cobra.Command, args []string) {
+		project, _ := cmd.Flags().GetString("project")
+		length, _ := cmd.Flags().GetInt("length")
+		if project == "" {
+			logError("Error: --project is required")
+			return
+		}
+		dbType := "project"
+		if len(args) == 1 {
+			dbType = args[0]
+		}
+		prefix := strings.ToUpper(dbType)
+
+		apitoDir, err := getApitoDir()
+		if err != nil {
+			logError("Error finding home directory:", err)
+			return
+		}
+		projectDir := filepath.Join(apitoDir, project)
+		config, err := getConfig(projectDir)
+		if err != nil {
+			logError("Error reading project config:", err)
+			return
+		}
+
+		engine := config[prefix+"_DB_ENGINE"]
+		switch engine {
+		case "postgres", "mysql", "mariadb":
+		default:
+			logError(fmt.Sprintf("Error: the %s database of %s is %q, only postgres, mysql and mariadb passwords can be rotated", dbType, project, engine))
+			return
+		}
+
+		if !confirmSensitiveOperation(fmt.Sprintf("Replace the password of %s on the %s database of %s", config[prefix+"_DB_USER"], dbType, project), riskDestructive) {
+			return
+		}
+
+		if dryRunSkip("run ALTER USER for %s on the %s database and save the new password", config[prefix+"_DB_USER"], dbType) {
+			return
+		}
+		password, err := generatePassword(length, passwordCharset)
+		if err != nil {
+			logError("Error:", err)
+			return
+		}
+		if err := rotateDatabasePassword(projectDir, engine, prefix, config, password); err != nil {
+			logError("Error:", err)
+			return
+		}
+
+		fmt.Println(Green+"Password rotated for"+Reset, config[prefix+"_DB_USER"])
+		if pid, err := strconv.Atoi(config["ENGINE_PID"]); err == nil && isProcessRunning(pid) {
+			logWarn(fmt.Sprintf("The engine of %s is running with the old password, restart it with `apito stop -p %s` and `apito run -p %s`", project, project, project))
+		}
+	},
+}
+
+// rotateDatabasePassword sets password for the configured database user and
+// saves it to the config. When the config cannot be saved the old password
+// is set again, so the config and the database never disagree.
+func rotateDatabasePassword(projectDir, engine, prefix string, config map[string]string, password string) error {
+	db, err := openDatabase(engine, prefix, config)
+	if err != nil {
+		return fmt.Errorf("error connecting to database: %w", err)
+	}
+	defer db.Close()
+
+	// ALTER USER does not take parameters, so the password is inlined as a
+	// quoted literal, the old password may contain any character
+	alterUser := func(password string) error {
+		query := "ALTER USER CURRENT_USER WITH PASSWORD " + pq.QuoteLiteral(password)
+		if engine != "postgres" {
+			query = "ALTER USER CURRENT_USER() IDENTIFIED BY " + quoteMySQLLiteral(password)
+		}
+		_, err := db.Exec(query)
+		return err
+	}
+	if err := alterUser(password); err != nil {
+		return fmt.Errorf("error changing the password: %w", err)
+	}
+
+	oldPassword := config[prefix+"_DB_PASS"]
+	config[prefix+"_DB_PASS"] = password
+	if err := saveConfig(projectDir, config); err != nil {
+		if revertErr := alterUser(oldPassword); revertErr != nil {
+			// the new password is in neither the config nor the log, this
+			// is the only place it is shown, so it is printed unmasked
+			fmt.Fprintf(os.Stderr, "The new password of %s is: %s\n", config[prefix+"_DB_USER"], password)
+			return fmt.Errorf("error saving config: %w, and the old password could not be restored: %v", err, revertErr)
+		}
+		return fmt.Errorf("error saving config, the old password was restored: %w", err)
+	}
+	return nil
+}
+
+// quoteMySQLLiteral quotes s as a MySQL string literal
+func quoteMySQLLiteral(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
+
+// inspectDatabaseContainer reads the <prefix>_DB_* settings from the image,
+// published port and environment of a database container
+func inspectDatabaseContainer(name, prefix string) (map[string]string, error) {
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		return nil, fmt.Errorf("error creating Docker client: %w", err)
+	}
+	defer cli.Close()
+
+	info, err := cli.ContainerInspect(context.Background(), name)
+	if err != nil {
+		return nil, fmt.Errorf("error inspecting container: %w", err)
+	}
+	if info.State != nil && !info.State.Running {
+		logWarn("Container", name, "is not running, start it before running the engine")
+	}
+
+	image := strings.ToLower(info.Config.Image)
+	engine, defaultPort := "", ""
+	for _, db := range dbImages {
+		if strings.Contains(image, db.image) {
+			engine, defaultPort = db.engine, db.port
+			break
+		}
+	}
+	if engine == "" {
+		return nil, fmt.Errorf("image %s is not a postgres, mysql or mariadb image", info.Config.Image)
+	}
+
+	env := map[string]string{}
+	for _, e := range info.Config.Env {
+		if key, value, ok := strings.Cut(e, "="); ok {
+			env[key] = value
+		}
+	}
+
+	config := map[string]string{prefix + "_DB_ENGINE": engine}
+	switch engine {
+	case "postgres":
+		config[prefix+"_DB_USER"] = firstNonEmpty(env["POSTGRES_USER"], "postgres")
+		config[prefix+"_DB_PASS"] = env["POSTGRES_PASSWORD"]
+		config[prefix+"_DB_NAME"] = firstNonEmpty(env["POSTGRES_DB"], config[prefix+"_DB_USER"])
+	default:
+		user := firstNonEmpty(env["MARIADB_USER"], env["MYSQL_USER"])
+		pass := firstNonEmpty(env["MARIADB_PASSWORD"], env["MYSQL_PASSWORD"])
+		if user == "" {
+			user = "root"
+			pass = firstNonEmpty(env["MARIADB_ROOT_PASSWORD"], env["MYSQL_ROOT_PASSWORD"])
+		}
+		config[prefix+"_DB_USER"] = user
+		config[prefix+"_DB_PASS"] = pass
+		config[prefix+"_DB_NAME"] = firstNonEmpty(env["MARIADB_DATABASE"], env["MYSQL_DATABASE"])
+	}
+
+	// a published port is reachable from the engine on this machine, the
+	// container address only on linux without docker desktop
+	for port, bindings := range info.NetworkSettings.Ports {
+		if port.Port() != defaultPort || len(bindings) == 0 {
+			continue
+		}
+		config[prefix+"_DB_HOST"] = "localhost"
+		config[prefix+"_DB_PORT"] = bindings[0].HostPort
+	}
+	if config[prefix+"_DB_HOST"] == "" {
+		for _, network := range info.NetworkSettings.Networks {
+			if network.IPAddress != "" {
+				logWarn(fmt.Sprintf("Port %s of %s is not published, using the container address %s", defaultPort, name, network.IPAddress))
+				config[prefix+"_DB_HOST"] = network.IPAddress
+				config[prefix+"_DB_PORT"] = defaultPort
+				break
+			}
+		}
+	}
+	if config[prefix+"_DB_HOST"] == "" {
+		return nil, fmt.Errorf("port %s of %s is not published, recreate it with -p %s:%s", defaultPort, name, defaultPort, defaultPort)
+	}
+	return config, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
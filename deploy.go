@@ -20,23 +20,27 @@ var deployCmd = &cobra.Command{
 		project, _ := cmd.Flags().GetString("project")
 
 		if project == "" {
-			fmt.Println("Error: --project is required")
+			logError("Error: --project is required")
 			return
 		}
 
 		actionName := args[0]
 
+		if !confirmSensitiveOperation(fmt.Sprintf("Deploy %s to %s", project, actionName), riskNormal) {
+			return
+		}
+
 		switch actionName {
 		case "apito":
 			if err := deployApito(project); err != nil {
-				fmt.Println("Error deploying to Docker:", err)
+				logError("Error deploying to Docker:", err)
 			}
 		case "aws":
 			deployAWS(project)
 		case "google":
 			deployGoogle(project)
 		default:
-			fmt.Println("Invalid provider. Use 'apito'")
+			logError("Invalid provider. Use 'apito'")
 		}
 	},
 }
@@ -48,6 +52,9 @@ func deployApito(project string) error {
 	}
 	projectDir := filepath.Join(homeDir, ".apito", project)
 	zipFile := filepath.Join(homeDir, ".apito", fmt.Sprintf("%s.zip", project))
+	if dryRunSkip("write %s", zipFile) {
+		return nil
+	}
 
 	zipf, err := os.Create(zipFile)
 	if err != nil {
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/client"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	diskUsageCmd.Flags().Bool("prune", false, "Remove downloaded release archives, logs and old config backups")
+	diskUsageCmd.Flags().Int("keep-backups", 3, "Config backups kept per config by --prune")
+}
+
+var diskUsageCmd = &cobra.Command{
+	Use:   "disk-usage",
+	Short: "Show the disk space used by apito",
+	Long:  `Report the size of ~/.apito by category and the docker images built for the projects. With --prune the release archives left by downloads, the logs and all but the newest --keep-backups backups of every config are removed.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		prune, _ := cmd.Flags().GetBool("prune")
+		keepBackups, _ := cmd.Flags().GetInt("keep-backups")
+		if keepBackups < 0 {
+			logError("Error: --keep-backups must not be negative")
+			return
+		}
+
+		// only pruning writes to ~/.apito, the report runs without the lock
+		if prune {
+			if err := lockApitoDir(waitForLock); err != nil {
+				logError("Error:", err)
+				return
+			}
+		}
+
+		apitoDir, err := getApitoDir()
+		if err != nil {
+			logError("Error finding home directory:", err)
+			return
+		}
+
+		usage, err := getDiskUsage(apitoDir, keepBackups)
+		if err != nil {
+			logError("Error reading apito directory:", err)
+			return
+		}
+
+		if dockerSize, err := getDockerImagesSize(); err != nil {
+			logDebug("Skipping docker images:", err)
+		} else {
+			usage.add("docker images", "", dockerSize)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "CATEGORY\tSIZE\tPRUNABLE")
+		var total int64
+		for _, c := range diskUsageCategories {
+			prunable := "-"
+			if c.Prunable {
+				prunable = "yes"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", c.Name, formatBytes(usage.sizes[c.Name]), prunable)
+			total += usage.sizes[c.Name]
+		}
+		fmt.Fprintf(w, "total\t%s\t\n", formatBytes(total))
+		w.Flush()
+
+		if !prune {
+			return
+		}
+
+		var paths []string
+		for _, c := range diskUsageCategories {
+			if c.Prunable {
+				paths = append(paths, usage.paths[c.Name]...)
+			}
+		}
+		if len(paths) == 0 {
+			fmt.Println("Nothing to prune")
+			return
+		}
+		if !confirmSensitiveOperation(fmt.Sprintf("Remove %d files", len(paths)), riskDestructive) {
+			return
+		}
+		removePaths(paths)
+	},
+}
+
+type diskUsageCategory struct {
+	Name     string
+	Prunable bool
+}
+
+var diskUsageCategories = []diskUsageCategory{
+	{Name: "databases"},
+	{Name: "engine binaries"},
+	{Name: "console"},
+	{Name: "release downloads", Prunable: true},
+	{Name: "build archives"},
+	{Name: "snapshots"},
+	{Name: "config backups", Prunable: true},
+	{Name: "logs", Prunable: true},
+	{Name: "other"},
+	{Name: "docker images"},
+}
+
+type diskUsage struct {
+	sizes map[string]int64
+	paths map[string][]string
+}
+
+func (u *diskUsage) add(category, path string, size int64) {
+	u.sizes[category] += size
+	if path != "" {
+		u.paths[category] = append(u.paths[category], path)
+	}
+}
+
+// getDiskUsage sorts every file and directory in ~/.apito into a category,
+// config backups beyond the newest keepBackups of each config are prunable
+func getDiskUsage(apitoDir string, keepBackups int) (*diskUsage, error) {
+	usage := &diskUsage{sizes: map[string]int64{}, paths: map[string][]string{}}
+
+	entries, err := os.ReadDir(apitoDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return usage, nil
+		}
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		path := filepath.Join(apitoDir, entry.Name())
+		switch {
+		case entry.Name() == filepath.Dir(LogFile):
+			logs, _ := filepath.Glob(filepath.Join(path, "*"))
+			for _, log := range logs {
+				usage.add("logs", log, dirSize(log))
+			}
+		case !entry.IsDir() && strings.HasSuffix(entry.Name(), ".zip"):
+			usage.add("build archives", "", dirSize(path))
+		case entry.Name() == ConsoleDir:
+			usage.add("console", "", dirSize(path))
+		case entry.Name() == ConfigBackupDir:
+			addBackupUsage(usage, apitoDir, keepBackups)
+		case entry.IsDir():
+			addProjectUsage(usage, entry.Name(), path, keepBackups)
+		default:
+			usage.add("other", "", dirSize(path))
+		}
+	}
+
+	return usage, nil
+}
+
+func addProjectUsage(usage *diskUsage, project, projectDir string, keepBackups int) {
+	entries, err := os.ReadDir(projectDir)
+	if err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		path := filepath.Join(projectDir, entry.Name())
+		switch {
+		case ArrayContains(projectDataDirs, entry.Name()):
+			usage.add("databases", "", dirSize(path))
+		case entry.Name() == EngineLogFile:
+			usage.add("logs", path, dirSize(path))
+		case entry.Name() == SnapshotDir:
+			usage.add("snapshots", "", dirSize(path))
+		case entry.Name() == ConfigBackupDir:
+			addBackupUsage(usage, projectDir, keepBackups)
+		case entry.Name() == project:
+			usage.add("engine binaries", "", dirSize(path))
+		case strings.HasPrefix(entry.Name(), "engine-") && strings.HasSuffix(entry.Name(), ".zip"):
+			usage.add("release downloads", path, dirSize(path))
+		default:
+			usage.add("other", "", dirSize(path))
+		}
+	}
+}
+
+// addBackupUsage counts the config backups in dir, only the ones older than
+// the newest keep are pruned
+func addBackupUsage(usage *diskUsage, dir string, keep int) {
+	backups, err := listConfigBackups(dir)
+	if err != nil {
+		return
+	}
+	for i, b := range backups {
+		path := ""
+		if i >= keep {
+			path = b.Path
+		}
+		usage.add("config backups", path, dirSize(b.Path))
+	}
+}
+
+func dirSize(path string) int64 {
+	var size int64
+	filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
+
+// getDockerImagesSize returns the size of the images built by `apito build docker`
+func getDockerImagesSize() (int64, error) {
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		return 0, err
+	}
+	defer cli.Close()
+
+	images, err := cli.ImageList(context.Background(), image.ListOptions{
+		Filters: filters.NewArgs(filters.Arg("reference", "apito.io/projects/*")),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var size int64
+	for _, img := range images {
+		size += img.Size
+	}
+	return size, nil
+}
+
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
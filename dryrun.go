@@ -0,0 +1,24 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// dryRun is set by --dry-run, commands then print what they would change
+// instead of changing it
+var dryRun bool
+
+// errDryRun is returned by the HTTP client for requests that would change
+// something on a server
+var errDryRun = errors.New("not sent in dry run")
+
+// dryRunSkip prints the action when --dry-run is set and reports whether it
+// must be skipped
+func dryRunSkip(format string, a ...interface{}) bool {
+	if !dryRun {
+		return false
+	}
+	fmt.Println(Cyan + "[dry-run] would " + redactOutput(fmt.Sprintf(format, a...)) + Reset)
+	return true
+}
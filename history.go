@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// HistoryFile in ~/.apito records every command that was run, it is trimmed
+// to the last historyKeepEntries once it grows above historyMaxSize
+const HistoryFile = "history.log"
+
+const (
+	historyMaxSize     = 1 << 20
+	historyKeepEntries = 1000
+)
+
+func init() {
+	historyCmd.Flags().Int("last", 20, "Number of commands to show, 0 for all")
+}
+
+var historyCmd = &cobra.Command{
+	Use:         "history",
+	Short:       "Show the commands run on this machine",
+	Annotations: map[string]string{explicitProjectAnnotation: "true"},
+	Long:        `Show the commands recorded in ~/.apito/history.log with the time, user, project, outcome and duration of each, oldest first. Secrets in the arguments are masked before they are recorded. With --project only the commands of that project are shown.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		project, _ := cmd.Flags().GetString("project")
+		last, _ := cmd.Flags().GetInt("last")
+
+		entries, err := readHistory()
+		if err != nil {
+			logError("Error reading history:", err)
+			return
+		}
+		if project != "" {
+			var filtered []historyEntry
+			for _, e := range entries {
+				if e.Project == project {
+					filtered = append(filtered, e)
+				}
+			}
+			entries = filtered
+		}
+		if last > 0 && len(entries) > last {
+			entries = entries[len(entries)-last:]
+		}
+		if len(entries) == 0 {
+			fmt.Println("No commands recorded yet")
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "TIME\tUSER\tPROJECT\tSTATUS\tDURATION\tCOMMAND")
+		for _, e := range entries {
+			status := Green + "ok" + Reset
+			if !e.Success {
+				status = Red + "failed" + Reset
+			}
+			duration := (time.Duration(e.DurationMs) * time.Millisecond).Round(time.Millisecond)
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", e.Time.Local().Format("2006-01-02 15:04:05"), valueOrDash(e.User), valueOrDash(e.Project), status, duration, strings.Join(append([]string{"apito"}, e.Args...), " "))
+		}
+		w.Flush()
+	},
+}
+
+type historyEntry struct {
+	Time       time.Time `json:"time"`
+	User       string    `json:"user"`
+	Project    string    `json:"project,omitempty"`
+	Command    string    `json:"command"`
+	Args       []string  `json:"args"`
+	Success    bool      `json:"success"`
+	DurationMs int64     `json:"duration_ms"`
+}
+
+func getHistoryPath() (string, error) {
+	apitoDir, err := getApitoDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(apitoDir, HistoryFile), nil
+}
+
+// recordHistory appends the executed command to the history. Like telemetry
+// it fails silently, the history must never get in the way of the command.
+func recordHistory(cmd *cobra.Command, args []string, duration time.Duration, success bool) {
+	if cmd == nil || cmd.Hidden || cmd == historyCmd {
+		return
+	}
+
+	path, err := getHistoryPath()
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return
+	}
+
+	entry := historyEntry{
+		Time:       time.Now().UTC(),
+		Command:    cmd.CommandPath(),
+		Args:       redactArgs(args),
+		Success:    success,
+		DurationMs: duration.Milliseconds(),
+	}
+	if u, err := user.Current(); err == nil {
+		entry.User = u.Username
+	}
+	if flag := cmd.Flags().Lookup("project"); flag != nil {
+		entry.Project = flag.Value.String()
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return
+	}
+	_, err = f.Write(append(line, '\n'))
+	f.Close()
+	if err != nil {
+		return
+	}
+
+	if info, err := os.Stat(path); err == nil && info.Size() > historyMaxSize {
+		trimHistory(path)
+	}
+}
+
+// trimHistory keeps the last historyKeepEntries of the history
+func trimHistory(path string) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	lines := bytes.SplitAfter(bytes.TrimSpace(data), []byte("\n"))
+	if len(lines) <= historyKeepEntries {
+		return
+	}
+	lines = lines[len(lines)-historyKeepEntries:]
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(bytes.Join(lines, nil), '\n'), 0600); err != nil {
+		return
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+	}
+}
+
+func readHistory() ([]historyEntry, error) {
+	path, err := getHistoryPath()
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []historyEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e historyEntry
+		if json.Unmarshal(scanner.Bytes(), &e) == nil {
+			entries = append(entries, e)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// redactArgs masks secrets in command line arguments: KEY=value pairs, the
+// values of flags such as --token and the value following a sensitive config
+// key, as in config set projects.blog.system_db_pass <value>
+func redactArgs(args []string) []string {
+	redacted := make([]string, len(args))
+	maskNext := false
+	for i, arg := range args {
+		switch {
+		case maskNext:
+			redacted[i] = maskedValue
+			maskNext = false
+			continue
+		case strings.HasPrefix(arg, "--"):
+			name, _, hasValue := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+			if isSensitiveKey(strings.ReplaceAll(name, "-", "_")) {
+				if hasValue {
+					redacted[i] = "--" + name + "=" + maskedValue
+				} else {
+					redacted[i] = arg
+					maskNext = true
+				}
+				continue
+			}
+		case isSensitiveAssignment(arg):
+			// the whole value is masked, it may contain spaces when it was
+			// quoted on the command line
+			key, _, _ := strings.Cut(arg, "=")
+			redacted[i] = key + "=" + maskedValue
+			continue
+		case !strings.HasPrefix(arg, "-") && !strings.ContainsAny(arg, "= "):
+			// a dot-path key ends with the .env key, so checking the whole
+			// path for a sensitive suffix covers both forms
+			maskNext = isSensitiveKey(strings.NewReplacer(".", "_", "-", "_").Replace(arg))
+		}
+		redacted[i] = redactSecrets(arg)
+	}
+	return redacted
+}
+
+// isSensitiveAssignment reports whether arg is a KEY=value pair of a secret
+func isSensitiveAssignment(arg string) bool {
+	key, _, ok := strings.Cut(arg, "=")
+	return ok && !strings.HasPrefix(key, "-") && isSensitiveKey(key)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRedactArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{
+			name: "project config key",
+			args: []string{"config", "set", "projects.blog.system_db_pass", "hunter2"},
+			want: []string{"config", "set", "projects.blog.system_db_pass", maskedValue},
+		},
+		{
+			name: "cli config key",
+			args: []string{"config", "set", "token", "s3cr3t"},
+			want: []string{"config", "set", "token", maskedValue},
+		},
+		{
+			name: "env config key",
+			args: []string{"config", "set", "SYSTEM_DB_PASS", "hunter2"},
+			want: []string{"config", "set", "SYSTEM_DB_PASS", maskedValue},
+		},
+		{
+			name: "other config key",
+			args: []string{"config", "set", "update.check", "false"},
+			want: []string{"config", "set", "update.check", "false"},
+		},
+		{
+			name: "flag with separate value",
+			args: []string{"login", "--token", "s3cr3t"},
+			want: []string{"login", "--token", maskedValue},
+		},
+		{
+			name: "flag with inline value",
+			args: []string{"login", "--token=s3cr3t"},
+			want: []string{"login", "--token=" + maskedValue},
+		},
+		{
+			name: "key value pair",
+			args: []string{"create", "project", "--set", "SYSTEM_DB_PASS=hunter2"},
+			want: []string{"create", "project", "--set", "SYSTEM_DB_PASS=" + maskedValue},
+		},
+		{
+			name: "quoted flag value with spaces",
+			args: []string{"db", "connect", "--password=a b"},
+			want: []string{"db", "connect", "--password=" + maskedValue},
+		},
+		{
+			name: "quoted separate flag value with spaces",
+			args: []string{"db", "connect", "--password", "a b"},
+			want: []string{"db", "connect", "--password", maskedValue},
+		},
+		{
+			name: "quoted key value pair with spaces",
+			args: []string{"create", "project", "--set", "PROJECT_DB_PASS=a b"},
+			want: []string{"create", "project", "--set", "PROJECT_DB_PASS=" + maskedValue},
+		},
+		{
+			name: "no secrets",
+			args: []string{"run", "-p", "blog", "--wait"},
+			want: []string{"run", "-p", "blog", "--wait"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := redactArgs(tt.args); !slices.Equal(got, tt.want) {
+				t.Errorf("redactArgs(%q) = %q, want %q", tt.args, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// debugHTTP is set by --debug-http and dumps every request and response
+var debugHTTP bool
+
+// requestTimeout is set by --timeout and overrides TIMEOUT in ~/.apito/.env
+var requestTimeout time.Duration
+
+const DefaultRequestTimeout = 30 * time.Second
+
+const debugBodyLimit = 2048
+
+// headers containing any of these words are redacted
+var sensitiveHeaders = []string{"auth", "cookie", "key", "secret", "token"}
+var sensitiveParams = []string{"token", "key", "access_token", "state"}
+var sensitiveBodyPattern = regexp.MustCompile(`(?i)("[a-z_]*(token|password|secret|key|auth)[a-z_]*"\s*:\s*)"[^"]*"`)
+
+// getRequestTimeout returns the timeout for API requests, --timeout takes
+// precedence over TIMEOUT in ~/.apito/.env
+func getRequestTimeout() time.Duration {
+	if requestTimeout > 0 {
+		return requestTimeout
+	}
+	return getConfigDuration("TIMEOUT", DefaultRequestTimeout)
+}
+
+// getDownloadTimeout returns the timeout for downloading release assets from
+// DOWNLOAD_TIMEOUT in ~/.apito/.env, by default downloads never time out
+func getDownloadTimeout() time.Duration {
+	return getConfigDuration("DOWNLOAD_TIMEOUT", 0)
+}
+
+func getConfigDuration(key string, fallback time.Duration) time.Duration {
+	config, err := getGlobalConfig()
+	if err != nil || config[key] == "" {
+		return fallback
+	}
+
+	d, err := time.ParseDuration(config[key])
+	if err != nil {
+		logWarn(fmt.Sprintf("Invalid %s %q in config, using %s", key, config[key], fallback))
+		return fallback
+	}
+	return d
+}
+
+// newHTTPClient returns the client every request of the cli goes through, a
+// zero timeout means no timeout which is used for downloads
+func newHTTPClient(timeout time.Duration) *http.Client {
+	return newHTTPClientWithTLS(timeout, nil)
+}
+
+// newHTTPClientWithTLS is newHTTPClient with a custom TLS config, e.g. for
+// servers that require a client certificate
+func newHTTPClientWithTLS(timeout time.Duration, tlsConfig *tls.Config) *http.Client {
+	transport := http.DefaultTransport
+	if tlsConfig != nil {
+		t := http.DefaultTransport.(*http.Transport).Clone()
+		t.TLSClientConfig = tlsConfig
+		transport = t
+	}
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: &tracingTransport{base: transport},
+	}
+}
+
+type tracingTransport struct {
+	base http.RoundTripper
+}
+
+func (t *tracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	logDebug(req.Method, sanitizeURL(req.URL))
+
+	if debugHTTP {
+		var body []byte
+		if req.Body != nil && req.GetBody != nil {
+			if b, err := req.GetBody(); err == nil {
+				body, _ = io.ReadAll(io.LimitReader(b, debugBodyLimit))
+				b.Close()
+			}
+		}
+		printHTTPDump(fmt.Sprintf("> %s %s", req.Method, sanitizeURL(req.URL)), req.Header, req.Header.Get("Content-Type"), body)
+	}
+
+	if req.Method != http.MethodGet && req.Method != http.MethodHead && dryRunSkip("send %s %s", req.Method, sanitizeURL(req.URL)) {
+		return nil, errDryRun
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		logDebug(req.Method, sanitizeURL(req.URL), "failed after", time.Since(start).Round(time.Millisecond), err)
+		return nil, err
+	}
+
+	logDebug(req.Method, sanitizeURL(req.URL), resp.StatusCode, time.Since(start).Round(time.Millisecond))
+
+	if debugHTTP {
+		// peek at the start of the body and put it back for the caller
+		prefix, _ := io.ReadAll(io.LimitReader(resp.Body, debugBodyLimit))
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(prefix), resp.Body), resp.Body}
+
+		title := fmt.Sprintf("< %s (%s)", resp.Status, time.Since(start).Round(time.Millisecond))
+		printHTTPDump(title, resp.Header, resp.Header.Get("Content-Type"), prefix)
+	}
+
+	return resp, nil
+}
+
+func printHTTPDump(title string, header http.Header, contentType string, body []byte) {
+	var dump strings.Builder
+	dump.WriteString(title + "\n")
+	for name, values := range header {
+		value := strings.Join(values, ", ")
+		for _, h := range sensitiveHeaders {
+			if strings.Contains(strings.ToLower(name), h) {
+				value = "[REDACTED]"
+			}
+		}
+		dump.WriteString(fmt.Sprintf("  %s: %s\n", name, value))
+	}
+
+	if len(body) > 0 {
+		if isTextContent(contentType) {
+			text := sensitiveBodyPattern.ReplaceAllString(string(body), `$1"[REDACTED]"`)
+			if len(body) == debugBodyLimit {
+				text += "... (truncated)"
+			}
+			dump.WriteString("  " + text + "\n")
+		} else {
+			dump.WriteString(fmt.Sprintf("  [%s body not shown]\n", contentType))
+		}
+	}
+
+	fmt.Fprint(os.Stderr, Gray+dump.String()+Reset)
+}
+
+func sanitizeURL(u *url.URL) string {
+	sanitized := *u
+	sanitized.User = nil
+	query := sanitized.Query()
+	for _, p := range sensitiveParams {
+		if query.Has(p) {
+			query.Set(p, "REDACTED")
+		}
+	}
+	sanitized.RawQuery = query.Encode()
+	return sanitized.String()
+}
+
+func isTextContent(contentType string) bool {
+	return strings.Contains(contentType, "json") ||
+		strings.Contains(contentType, "text") ||
+		strings.Contains(contentType, "xml") ||
+		strings.Contains(contentType, "graphql")
+}
@@ -5,14 +5,18 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strconv"
+	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
 )
 
 var listCmd = &cobra.Command{
-	Use:   "list",
-	Short: "List projects, functions, or models",
-	Long:  `List projects, functions, or models in the Apito CLI.`,
+	Annotations: map[string]string{explicitProjectAnnotation: "true"},
+	Use:         "list",
+	Short:       "List projects, functions, or models",
+	Long:        `List projects, functions, or models in the Apito CLI.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		project, _ := cmd.Flags().GetString("project")
 		if project == "" {
@@ -25,38 +29,86 @@ var listCmd = &cobra.Command{
 
 func init() {
 	listCmd.Flags().StringP("project", "p", "", "Project name")
+	listCmd.RegisterFlagCompletionFunc("project", completeProjects)
 }
 
 func listProjects() {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
-		fmt.Println("Error finding home directory:", err)
+		logError("Error finding home directory:", err)
 		return
 	}
 	apitoDir := filepath.Join(homeDir, ".apito")
 	files, err := ioutil.ReadDir(apitoDir)
 	if err != nil {
-		fmt.Println("Error reading apito directory:", err)
+		logError("Error reading apito directory:", err)
 		return
 	}
 
+	defaultProject := ""
+	if config, err := getGlobalConfig(); err == nil {
+		defaultProject = config["DEFAULT_PROJECT"]
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "NAME\tFULL NAME\tSYSTEM DB\tPROJECT DB\tCREATED\tSTATUS")
+
 	for _, f := range files {
-		if f.IsDir() {
-			fmt.Println(f.Name())
+		if !f.IsDir() {
+			continue
+		}
+
+		// only directories with a config file are projects
+		envMap, err := getConfig(filepath.Join(apitoDir, f.Name()))
+		if err != nil {
+			continue
+		}
+
+		created := "-"
+		if t, err := time.Parse(time.RFC3339, envMap["CREATED_AT"]); err == nil {
+			created = t.Local().Format("2006-01-02 15:04")
 		}
+
+		status := "stopped"
+		if pid, err := strconv.Atoi(envMap["ENGINE_PID"]); err == nil && isProcessRunning(pid) {
+			status = fmt.Sprintf("running (pid %d)", pid)
+		}
+
+		name := f.Name()
+		if name == defaultProject {
+			name += " (default)"
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			name,
+			valueOrDash(envMap["PROJECT_NAME"]),
+			valueOrDash(envMap["SYSTEM_DB_ENGINE"]),
+			valueOrDash(envMap["PROJECT_DB_ENGINE"]),
+			created,
+			status,
+		)
+	}
+
+	w.Flush()
+}
+
+func valueOrDash(value string) string {
+	if value == "" {
+		return "-"
 	}
+	return value
 }
 
 func listFunctions(project string) {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
-		fmt.Println("Error finding home directory:", err)
+		logError("Error finding home directory:", err)
 		return
 	}
 	functionsDir := filepath.Join(homeDir, ".apito", project, "functions")
 	files, err := ioutil.ReadDir(functionsDir)
 	if err != nil {
-		fmt.Println("Error reading functions directory:", err)
+		logError("Error reading functions directory:", err)
 		return
 	}
 
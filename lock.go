@@ -0,0 +1,85 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// LockFile in ~/.apito is held by commands that change configs, binaries or
+// databases so concurrent invocations do not interleave their writes
+const LockFile = ".lock"
+
+// lockAnnotation marks the commands that take the lock before they run
+const lockAnnotation = "lock"
+
+var mutatingAnnotations = map[string]string{lockAnnotation: "true"}
+
+// waitForLock is set by --wait to wait for the lock instead of failing
+var waitForLock bool
+
+var lockFile *os.File
+
+// lockApitoDir takes an exclusive lock on ~/.apito/.lock, with wait it blocks
+// until the other apito process is done
+func lockApitoDir(wait bool) error {
+	apitoDir, err := getApitoDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(apitoDir, 0755); err != nil {
+		return fmt.Errorf("error creating apito directory: %w", err)
+	}
+
+	f, err := os.OpenFile(filepath.Join(apitoDir, LockFile), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("error opening lock file: %w", err)
+	}
+
+	err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		holder := lockHolder(f)
+		if !wait {
+			f.Close()
+			return fmt.Errorf("another apito process is running%s, use --wait to wait for it", holder)
+		}
+		logInfo("Waiting for another apito process" + holder + " to finish...")
+		err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX)
+	}
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("error locking %s: %w", f.Name(), err)
+	}
+
+	// the pid is only informational, the flock is released by the kernel
+	// when the process exits
+	f.Truncate(0)
+	f.WriteAt([]byte(strconv.Itoa(os.Getpid())), 0)
+	lockFile = f
+	return nil
+}
+
+// unlockApitoDir releases the lock if this process holds it
+func unlockApitoDir() {
+	if lockFile == nil {
+		return
+	}
+	lockFile.Truncate(0)
+	syscall.Flock(int(lockFile.Fd()), syscall.LOCK_UN)
+	lockFile.Close()
+	lockFile = nil
+}
+
+// lockHolder describes the process holding the lock for error messages
+func lockHolder(f *os.File) string {
+	data := make([]byte, 32)
+	n, _ := f.ReadAt(data, 0)
+	if pid := strings.TrimSpace(string(data[:n])); pid != "" {
+		return " (pid " + pid + ")"
+	}
+	return ""
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// LogFile is the debug log every command writes to, it is meant to be
+// attached to bug reports
+const LogFile = "logs/cli.log"
+
+var verboseOutput bool
+var quietOutput bool
+var logWriter io.Writer = io.Discard
+
+// errorLogged is set once an error was reported, commands print their errors
+// instead of returning them so this is how the outcome of a run is known
+var errorLogged bool
+
+// exitCode is set by commands that report their outcome through a specific
+// exit status, main exits with it once history and telemetry are recorded
+var exitCode int
+
+// initLogger opens the debug log in ~/.apito/logs. The log is best effort, a
+// command never fails because the log file cannot be written.
+func initLogger(verbose, quiet bool) {
+	verboseOutput = verbose
+	quietOutput = quiet && !verbose
+
+	apitoDir, err := getApitoDir()
+	if err != nil {
+		return
+	}
+
+	logPath := filepath.Join(apitoDir, LogFile)
+	if err := os.MkdirAll(filepath.Dir(logPath), 0755); err != nil {
+		return
+	}
+
+	f, err := os.OpenFile(logPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return
+	}
+	logWriter = f
+}
+
+var ansiPattern = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// sprintln formats like fmt.Println without the trailing newline
+func sprintln(a ...interface{}) string {
+	return strings.TrimSuffix(fmt.Sprintln(a...), "\n")
+}
+
+func writeLog(level string, a ...interface{}) {
+	message := redactSecrets(ansiPattern.ReplaceAllString(sprintln(a...), ""))
+	fmt.Fprintf(logWriter, "%s [%s] %s\n", time.Now().Format(time.RFC3339), level, message)
+}
+
+// logDebug writes to the log file, and to stderr with --verbose
+func logDebug(a ...interface{}) {
+	writeLog("DEBUG", a...)
+	if verboseOutput {
+		fmt.Fprintln(os.Stderr, Gray+"[debug] "+redactOutput(sprintln(a...))+Reset)
+	}
+}
+
+// logInfo writes progress messages to stdout unless --quiet is set
+func logInfo(a ...interface{}) {
+	writeLog("INFO", a...)
+	if !quietOutput {
+		fmt.Println(redactOutput(sprintln(a...)))
+	}
+}
+
+// logWarn writes warnings to stderr unless --quiet is set
+func logWarn(a ...interface{}) {
+	writeLog("WARN", a...)
+	if !quietOutput {
+		fmt.Fprintln(os.Stderr, Yellow+"Warning: "+redactOutput(sprintln(a...))+Reset)
+	}
+}
+
+// logError always writes to stderr
+func logError(a ...interface{}) {
+	errorLogged = true
+	writeLog("ERROR", a...)
+	fmt.Fprintln(os.Stderr, redactOutput(sprintln(a...)))
+}
@@ -1,58 +1,173 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
-	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 )
 
+// LoginURL is the default login page, LOGIN_URL in ~/.apito/.env overrides
+// it. The page is expected to redirect to redirect_uri with the token and the
+// state it was given as query parameters. This contract is assumed by the cli
+// and not documented by the Apito cloud yet.
+const LoginURL = "https://app.apito.io/login"
+const loginCallbackAddr = "localhost:5555"
+const loginTimeout = 5 * time.Minute
+
+func init() {
+	loginCmd.Flags().String("token", "", "Store the given token without opening the browser")
+	loginCmd.Flags().Bool("token-stdin", false, "Read the token from stdin, e.g. from a CI secret")
+	loginCmd.Flags().String("token-file", "", "Read the token from a file")
+	loginCmd.MarkFlagsMutuallyExclusive("token", "token-stdin", "token-file")
+}
+
 var loginCmd = &cobra.Command{
-	Use:   "login",
-	Short: "Login to Apito CLI",
-	Long:  `Login to Apito CLI using OAuth.`,
+	Annotations: mutatingAnnotations,
+	Use:         "login",
+	Short:       "Login to Apito Cloud",
+	Long:        `Login to Apito Cloud in the browser and store the token in ~/.apito/.env`,
 	Run: func(cmd *cobra.Command, args []string) {
-		project := ""
-		startLoginServer(project)
+		token, _ := cmd.Flags().GetString("token")
+		tokenStdin, _ := cmd.Flags().GetBool("token-stdin")
+		tokenFile, _ := cmd.Flags().GetString("token-file")
+
+		switch {
+		case tokenStdin:
+			var err error
+			token, err = readSecret(os.Stdin)
+			if err != nil {
+				logError("Error reading token from stdin:", err)
+				return
+			}
+		case tokenFile != "":
+			f, err := os.Open(tokenFile)
+			if err != nil {
+				logError("Error reading token file:", err)
+				return
+			}
+			token, err = readSecret(f)
+			f.Close()
+			if err != nil {
+				logError("Error reading token file:", err)
+				return
+			}
+		}
+
+		if token == "" {
+			var err error
+			token, err = startLoginServer()
+			if err != nil {
+				logError("Error logging in:", err)
+				return
+			}
+		}
+
+		if err := updateGlobalConfig("TOKEN", token); err != nil {
+			logError("Error saving token:", err)
+			return
+		}
+
+		fmt.Println(Green + "Login successful." + Reset)
 	},
 }
 
-func startLoginServer(project string) {
+// startLoginServer opens the Apito login page and waits for the browser to
+// redirect back to the local callback server with the token
+func startLoginServer() (string, error) {
+	state, err := randomHex(16)
+	if err != nil {
+		return "", err
+	}
 
-	homeDir, err := os.UserHomeDir()
+	listener, err := net.Listen("tcp", loginCallbackAddr)
 	if err != nil {
-		fmt.Println("Error finding home directory:", err)
-		return
+		return "", fmt.Errorf("error starting login server: %w", err)
 	}
 
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+	tokens := make(chan string, 1)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		token := r.URL.Query().Get("token")
-		if token != "" {
-			projectDir := filepath.Join(homeDir, ".apito", project)
-			err := updateConfig(projectDir, "TOKEN", token)
-			if err != nil {
-				fmt.Println("Error updating config:", err)
-				return
-			}
-			fmt.Fprintln(w, "Login successful. You can close this window.")
-		} else {
+		if token == "" || r.URL.Query().Get("state") != state {
 			fmt.Fprintln(w, "Invalid login attempt.")
+			return
+		}
+		fmt.Fprintln(w, "Login successful. You can close this window.")
+		select {
+		case tokens <- token:
+		default:
 		}
 	})
 
-	go func() {
-		fmt.Println("Starting login server on http://localhost:5555")
-		if err := http.ListenAndServe(":5555", nil); err != nil {
-			fmt.Println("Error starting server:", err)
-		}
-	}()
+	server := &http.Server{Handler: mux}
+	go server.Serve(listener)
+	defer server.Shutdown(context.Background())
+
+	query := url.Values{}
+	query.Set("redirect_uri", "http://"+loginCallbackAddr+"/")
+	query.Set("state", state)
+	base := LoginURL
+	if config, err := getGlobalConfig(); err == nil && config["LOGIN_URL"] != "" {
+		base = config["LOGIN_URL"]
+	}
+	loginURL := base + "?" + query.Encode()
+
+	fmt.Println("Opening the login page in your browser...")
+	fmt.Println("If it does not open, visit:", loginURL)
+	if err := openBrowser(loginURL); err != nil {
+		logError("Error opening browser:", err)
+	}
+
+	select {
+	case token := <-tokens:
+		return token, nil
+	case <-time.After(loginTimeout):
+		return "", fmt.Errorf("timed out waiting for login")
+	}
+}
+
+// openBrowser opens the url in the default browser of the platform
+func openBrowser(url string) error {
+	switch runtime.GOOS {
+	case "darwin":
+		return exec.Command("open", url).Start()
+	case "windows":
+		return exec.Command("rundll32", "url.dll,FileProtocolHandler", url).Start()
+	default:
+		return exec.Command("xdg-open", url).Start()
+	}
+}
+
+// readSecret reads a secret from r, surrounding whitespace such as the
+// newline of echo is removed
+func readSecret(r io.Reader) (string, error) {
+	data, err := io.ReadAll(io.LimitReader(r, 64*1024))
+	if err != nil {
+		return "", err
+	}
+	secret := strings.TrimSpace(string(data))
+	if secret == "" {
+		return "", fmt.Errorf("no secret given")
+	}
+	return secret, nil
+}
 
-	// Open the login URL in the default browser
-	loginURL := "https://example.com/oauth/login"
-	if err := exec.Command("open", loginURL).Start(); err != nil {
-		fmt.Println("Error opening browser:", err)
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("error generating random value: %w", err)
 	}
+	return hex.EncodeToString(b), nil
 }
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// EngineLogFile is written next to the project config by apito run, every
+// line is prefixed with the time it was written
+const EngineLogFile = "engine.log"
+
+// logLevels from least to most severe, lines without a level count as info
+var logLevels = []string{"debug", "info", "warn", "error", "fatal"}
+
+var logLevelPattern = regexp.MustCompile(`(?i)\b(debug|info|warn|warning|error|err|fatal|panic)\b`)
+
+func init() {
+	logsCmd.Flags().String("grep", "", "Only show lines matching the regular expression")
+	logsCmd.Flags().Duration("since", 0, "Only show lines written in this period, e.g. 15m")
+	logsCmd.Flags().String("level", "", "Only show lines of this level or more severe: "+strings.Join(logLevels, ", "))
+	logsCmd.Flags().Bool("cli", false, "Show the cli debug log instead of the engine log")
+}
+
+var logsCmd = &cobra.Command{
+	Use:   "logs",
+	Short: "Show the engine or cli logs",
+	Long:  `Show the output the engine of the project wrote while started with apito run, or the cli debug log with --cli, filtered by pattern, age and level.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		project, _ := cmd.Flags().GetString("project")
+		grep, _ := cmd.Flags().GetString("grep")
+		since, _ := cmd.Flags().GetDuration("since")
+		level, _ := cmd.Flags().GetString("level")
+		cliLog, _ := cmd.Flags().GetBool("cli")
+
+		apitoDir, err := getApitoDir()
+		if err != nil {
+			logError("Error finding home directory:", err)
+			return
+		}
+
+		var path string
+		if cliLog {
+			path = filepath.Join(apitoDir, LogFile)
+		} else {
+			if project == "" {
+				logError("Error: --project is required")
+				return
+			}
+			path = filepath.Join(apitoDir, project, EngineLogFile)
+		}
+
+		filter := logFilter{minLevel: -1}
+		if grep != "" {
+			filter.pattern, err = regexp.Compile(grep)
+			if err != nil {
+				logError("Error: invalid --grep:", err)
+				return
+			}
+		}
+		if since > 0 {
+			filter.since = time.Now().Add(-since)
+		}
+		if level != "" {
+			filter.minLevel = logLevelIndex(level)
+			if filter.minLevel < 0 {
+				logError("Error: --level must be one of", strings.Join(logLevels, ", "))
+				return
+			}
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				logError("No logs found at", path)
+				return
+			}
+			logError("Error opening log:", err)
+			return
+		}
+		defer f.Close()
+
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			if filter.match(scanner.Text()) {
+				fmt.Println(scanner.Text())
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			logError("Error reading log:", err)
+		}
+	},
+}
+
+type logFilter struct {
+	pattern  *regexp.Regexp
+	since    time.Time
+	minLevel int
+}
+
+// match reports whether a log line passes the filter, both log files start
+// every line with an RFC3339 timestamp
+func (f logFilter) match(line string) bool {
+	if f.pattern != nil && !f.pattern.MatchString(line) {
+		return false
+	}
+	if !f.since.IsZero() {
+		stamp, _, _ := strings.Cut(line, " ")
+		t, err := time.Parse(time.RFC3339, stamp)
+		if err != nil || t.Before(f.since) {
+			return false
+		}
+	}
+	if f.minLevel >= 0 && lineLevel(line) < f.minLevel {
+		return false
+	}
+	return true
+}
+
+func logLevelIndex(level string) int {
+	switch strings.ToLower(level) {
+	case "warning":
+		level = "warn"
+	case "err":
+		level = "error"
+	case "panic":
+		level = "fatal"
+	}
+	for i, l := range logLevels {
+		if l == strings.ToLower(level) {
+			return i
+		}
+	}
+	return -1
+}
+
+// lineLevel returns the first level named in the line
+func lineLevel(line string) int {
+	if m := logLevelPattern.FindString(line); m != "" {
+		return logLevelIndex(m)
+	}
+	return logLevelIndex("info")
+}
+
+// timestampWriter prefixes every complete line written to it with the
+// current time, it is safe to share between stdout and stderr of a process
+type timestampWriter struct {
+	mu  sync.Mutex
+	w   io.Writer
+	buf []byte
+}
+
+func (t *timestampWriter) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.buf = append(t.buf, p...)
+	for {
+		i := bytes.IndexByte(t.buf, '\n')
+		if i < 0 {
+			break
+		}
+		if _, err := fmt.Fprintf(t.w, "%s %s\n", time.Now().Format(time.RFC3339), t.buf[:i]); err != nil {
+			return len(p), err
+		}
+		t.buf = t.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush writes a last line that did not end with a newline
+func (t *timestampWriter) Flush() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.buf) > 0 {
+		fmt.Fprintf(t.w, "%s %s\n", time.Now().Format(time.RFC3339), t.buf)
+		t.buf = nil
+	}
+}
@@ -1,20 +1,58 @@
 package main
 
 import (
-	"fmt"
 	"github.com/spf13/cobra"
 	"os"
+	"time"
 )
 
+// version is set at build time with -ldflags "-X main.version=v1.2.3"
+var version = "dev"
+
+// notifyCLIUpdate prints the result of the cli update check started before
+// the command ran
+var notifyCLIUpdate = func() {}
+
 func main() {
 	rootCmd := &cobra.Command{
-		Use:   "apito",
-		Short: "Apito CLI",
-		Args:  cobra.MinimumNArgs(1),
-		Long:  `Apito CLI to manage projects, functions, and more.`,
+		Use:     "apito",
+		Short:   "Apito CLI",
+		Args:    cobra.MinimumNArgs(1),
+		Long:    `Apito CLI to manage projects, functions, and more.`,
+		Version: version,
 	}
 	var project string
-	rootCmd.PersistentFlags().StringVarP(&project, "project", "p", "", "ver")
+	var verbose, quiet bool
+	rootCmd.PersistentFlags().StringVarP(&project, "project", "p", "", "Project name (default APITO_PROJECT, PROJECT in .apitorc or DEFAULT_PROJECT)")
+	rootCmd.PersistentFlags().BoolVar(&verbose, "verbose", false, "Print debug output such as HTTP requests and executed commands")
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "Only print errors and command results")
+	rootCmd.PersistentFlags().DurationVar(&requestTimeout, "timeout", 0, "Timeout for API requests (default TIMEOUT in ~/.apito/.env or 30s)")
+	rootCmd.PersistentFlags().BoolVar(&forceConfirm, "force", false, "Do not ask for confirmation")
+	rootCmd.PersistentFlags().BoolVar(&debugHTTP, "debug-http", false, "Print sanitized dumps of every HTTP request and response")
+	rootCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "Print the files, processes, containers and HTTP requests a command would change without changing them")
+	rootCmd.PersistentFlags().BoolVar(&showSecrets, "show-secrets", false, "Print passwords, keys and tokens instead of masking them")
+	rootCmd.PersistentFlags().BoolVar(&waitForLock, "wait", false, "Wait for another running apito process instead of failing")
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		initLogger(verbose, quiet)
+		logDebug("Running", cmd.CommandPath())
+		if flag := cmd.Flags().Lookup("project"); flag != nil && !flag.Changed && cmd.Annotations[explicitProjectAnnotation] == "" {
+			if p, source := resolveProject(); p != "" {
+				logDebug("Using project", p, "from", source)
+				cmd.Flags().Set("project", p)
+			}
+		}
+		notifyCLIUpdate = startCLIUpdateCheck(cmd)
+		if cmd.Annotations[lockAnnotation] != "" {
+			if err := lockApitoDir(waitForLock); err != nil {
+				// not a usage error, main prints it once
+				cmd.SilenceUsage = true
+				cmd.SilenceErrors = true
+				return err
+			}
+		}
+		return nil
+	}
+	rootCmd.RegisterFlagCompletionFunc("project", completeProjects)
 
 	rootCmd.AddCommand(createCmd)
 	rootCmd.AddCommand(buildCmd)
@@ -24,10 +62,41 @@ func main() {
 	rootCmd.AddCommand(listCmd)
 	rootCmd.AddCommand(deployCmd)
 	rootCmd.AddCommand(loginCmd)
-	rootCmd.AddCommand(changePassCmd)
+	rootCmd.AddCommand(completionCmd)
+	rootCmd.AddCommand(projectCmd)
+	rootCmd.AddCommand(queryCmd)
+	rootCmd.AddCommand(telemetryCmd)
+	rootCmd.AddCommand(resetCmd)
+	rootCmd.AddCommand(diskUsageCmd)
+	rootCmd.AddCommand(snapshotCmd)
+	rootCmd.AddCommand(openCmd)
+	rootCmd.AddCommand(schemaCmd)
+	rootCmd.AddCommand(versionsCmd)
+	rootCmd.AddCommand(logsCmd)
+	rootCmd.AddCommand(statusCmd)
+	rootCmd.AddCommand(configCmd)
+	rootCmd.AddCommand(useCmd)
+	rootCmd.AddCommand(versionCmd)
+	rootCmd.AddCommand(dbCmd)
+	rootCmd.AddCommand(historyCmd)
 
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
+	start := time.Now()
+	cmd, err := rootCmd.ExecuteC()
+	unlockApitoDir()
+	notifyCLIUpdate()
+	success := err == nil && !errorLogged && exitCode == 0
+	recordTelemetry(cmd, time.Since(start), success)
+	recordHistory(cmd, os.Args[1:], time.Since(start), success)
+	if err != nil {
+		logError(err)
+		os.Exit(1)
+	}
+	// commands report their errors with logError and return, scripts still
+	// need a failing exit status
+	if exitCode != 0 {
+		os.Exit(exitCode)
+	}
+	if errorLogged {
 		os.Exit(1)
 	}
 }
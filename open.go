@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+const DefaultConsoleURL = "https://app.apito.io"
+const DocsURL = "https://docs.apito.io"
+
+func init() {
+	openCmd.Flags().Bool("print", false, "Print the URL instead of opening it")
+}
+
+var openCmd = &cobra.Command{
+	Use:       "open [console|engine|graphql|docs]",
+	Short:     "Open the console, engine or docs in the browser",
+	Long:      `Open the console (default), the engine, its GraphQL endpoint or the documentation in the default browser. The engine URL is ENGINE_URL and the console URL is CONSOLE_URL from the project config.`,
+	ValidArgs: []string{"console", "engine", "graphql", "docs"},
+	Args:      cobra.MatchAll(cobra.MaximumNArgs(1), cobra.OnlyValidArgs),
+	Run: func(cmd *cobra.Command, args []string) {
+		project, _ := cmd.Flags().GetString("project")
+		printOnly, _ := cmd.Flags().GetBool("print")
+
+		target := "console"
+		if len(args) > 0 {
+			target = args[0]
+		}
+
+		url, err := getOpenURL(project, target)
+		if err != nil {
+			logError("Error:", err)
+			return
+		}
+
+		if printOnly {
+			fmt.Println(url)
+			return
+		}
+
+		logInfo("Opening", url)
+		if err := openBrowser(url); err != nil {
+			logError("Error opening browser:", err)
+			fmt.Println("Open this URL in your browser:", url)
+		}
+	},
+}
+
+// getOpenURL returns the URL of the target, console and engine URLs come
+// from the project config when a project is given
+func getOpenURL(project, target string) (string, error) {
+	if target == "docs" {
+		return DocsURL, nil
+	}
+
+	envMap := map[string]string{}
+	if project != "" {
+		apitoDir, err := getApitoDir()
+		if err != nil {
+			return "", err
+		}
+		envMap, err = getConfig(filepath.Join(apitoDir, project))
+		if err != nil {
+			return "", err
+		}
+	} else if target != "console" {
+		return "", fmt.Errorf("--project is required to open the %s", target)
+	}
+
+	switch target {
+	case "console":
+		if envMap["CONSOLE_URL"] != "" {
+			return envMap["CONSOLE_URL"], nil
+		}
+		return DefaultConsoleURL, nil
+	case "engine", "graphql":
+		endpoint, err := getEngineEndpoint(project, "", "")
+		if err != nil {
+			return "", err
+		}
+		if target == "graphql" {
+			return endpoint.URL + graphqlPath, nil
+		}
+		return endpoint.URL, nil
+	}
+	return "", fmt.Errorf("unknown target %s", target)
+}
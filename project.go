@@ -0,0 +1,285 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"github.com/spf13/cobra"
+)
+
+// projectDataDirs are the local database directories of a project
+var projectDataDirs = []string{"db", "memorydb"}
+
+func init() {
+	projectExportCmd.Flags().StringP("out", "o", "", "Output file (default <project>.tar.gz)")
+	projectExportCmd.Flags().Bool("skip-data", false, "Do not include the local database files")
+	projectExportCmd.ValidArgsFunction = completeProjects
+
+	projectImportCmd.Flags().StringP("file", "f", "", "Project archive to import")
+	projectImportCmd.Flags().StringP("name", "n", "", "Import the project under a different name")
+
+	projectCmd.AddCommand(projectExportCmd)
+	projectCmd.AddCommand(projectImportCmd)
+}
+
+var projectCmd = &cobra.Command{
+	Use:   "project",
+	Short: "Export or import projects",
+	Long:  `Export a project to an archive or import a project from an archive.`,
+}
+
+var projectExportCmd = &cobra.Command{
+	Use:   "export <project>",
+	Short: "Export a project to a tar.gz archive",
+	Long:  `Export the settings, functions and local database files of a project in ~/.apito/<project> to a tar.gz archive. The engine binary is not included.`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		project := args[0]
+		out, _ := cmd.Flags().GetString("out")
+		skipData, _ := cmd.Flags().GetBool("skip-data")
+
+		if out == "" {
+			out = fmt.Sprintf("%s.tar.gz", project)
+		}
+
+		if dryRunSkip("write %s", out) {
+			return
+		}
+		if err := exportProject(project, out, skipData); err != nil {
+			logError("Error exporting project:", err)
+			return
+		}
+
+		fmt.Println(Green + "Project exported successfully: " + out + Reset)
+	},
+}
+
+var projectImportCmd = &cobra.Command{
+	Annotations: mutatingAnnotations,
+	Use:         "import",
+	Short:       "Import a project from a tar.gz archive",
+	Long:        `Import a project archive created by 'apito project export' into ~/.apito/<project>.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		file, _ := cmd.Flags().GetString("file")
+		name, _ := cmd.Flags().GetString("name")
+
+		if file == "" {
+			logError("Error: --file is required")
+			return
+		}
+
+		if dryRunSkip("import %s into ~/.apito", file) {
+			return
+		}
+		project, err := importProject(file, name)
+		if err != nil {
+			logError("Error importing project:", err)
+			return
+		}
+
+		fmt.Println(Green + fmt.Sprintf("Project %s imported successfully!", project) + Reset)
+		fmt.Println(Blue + `The engine binary is not part of the archive, download it with` + Reset)
+		fmt.Println(Green + fmt.Sprintf(`> apito update engine -p %s`, project) + Reset)
+	},
+}
+
+func exportProject(project, out string, skipData bool) (err error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return fmt.Errorf("error finding home directory: %w", err)
+	}
+	projectDir := filepath.Join(homeDir, ".apito", project)
+
+	envMap, err := getConfig(projectDir)
+	if err != nil {
+		return err
+	}
+	// the PID only makes sense on this machine
+	delete(envMap, "ENGINE_PID")
+
+	// the archive holds the config with its database passwords
+	f, err := os.OpenFile(out, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("error creating archive: %w", err)
+	}
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+	// the writers are closed in order and their errors returned, otherwise
+	// a truncated archive would be reported as exported
+	defer func() {
+		for _, c := range []io.Closer{tw, gw, f} {
+			if closeErr := c.Close(); closeErr != nil && err == nil {
+				err = fmt.Errorf("error writing archive: %w", closeErr)
+			}
+		}
+		if err != nil {
+			os.Remove(out)
+		}
+	}()
+
+	env, err := godotenv.Marshal(envMap)
+	if err != nil {
+		return fmt.Errorf("error encoding config: %w", err)
+	}
+	env += "\n"
+
+	err = tw.WriteHeader(&tar.Header{
+		Name: filepath.ToSlash(filepath.Join(project, ConfigFile)),
+		Mode: 0600,
+		Size: int64(len(env)),
+	})
+	if err != nil {
+		return fmt.Errorf("error writing config to archive: %w", err)
+	}
+	if _, err := io.WriteString(tw, env); err != nil {
+		return fmt.Errorf("error writing config to archive: %w", err)
+	}
+
+	dirs := []string{"functions"}
+	if !skipData {
+		dirs = append(dirs, projectDataDirs...)
+	}
+
+	for _, dir := range dirs {
+		root := filepath.Join(projectDir, dir)
+		if _, err := os.Stat(root); os.IsNotExist(err) {
+			continue
+		}
+
+		err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			relPath, err := filepath.Rel(projectDir, path)
+			if err != nil {
+				return err
+			}
+
+			header, err := tar.FileInfoHeader(info, "")
+			if err != nil {
+				return err
+			}
+			header.Name = filepath.ToSlash(filepath.Join(project, relPath))
+			if err := tw.WriteHeader(header); err != nil {
+				return err
+			}
+			if info.IsDir() {
+				return nil
+			}
+
+			file, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+
+			_, err = io.Copy(tw, file)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("error adding %s to archive: %w", dir, err)
+		}
+	}
+
+	return nil
+}
+
+// importProject extracts the archive into ~/.apito and returns the name of
+// the imported project. A partially extracted project is removed again.
+func importProject(file, name string) (project string, err error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("error finding home directory: %w", err)
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return "", fmt.Errorf("error opening archive: %w", err)
+	}
+	defer f.Close()
+
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		return "", fmt.Errorf("error reading archive: %w", err)
+	}
+	defer gr.Close()
+
+	tr := tar.NewReader(gr)
+
+	var projectDir string
+	defer func() {
+		if err != nil && projectDir != "" {
+			os.RemoveAll(projectDir)
+		}
+	}()
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("error reading archive: %w", err)
+		}
+
+		archived, relPath, _ := strings.Cut(filepath.ToSlash(header.Name), "/")
+		if project == "" {
+			project = archived
+			if name != "" {
+				project = name
+			}
+			if err := validatePathName(project); err != nil {
+				return "", fmt.Errorf("invalid project name: %w", err)
+			}
+			dir := filepath.Join(homeDir, ".apito", project)
+			if _, err := os.Stat(dir); err == nil {
+				return "", fmt.Errorf("a project with the name %s already exists in %s", project, dir)
+			}
+			projectDir = dir
+		}
+
+		target := filepath.Join(projectDir, filepath.FromSlash(relPath))
+		if !strings.HasPrefix(target, projectDir+string(os.PathSeparator)) {
+			return "", fmt.Errorf("invalid file path in archive: %s", header.Name)
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return "", fmt.Errorf("error creating directory: %w", err)
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return "", fmt.Errorf("error creating directory: %w", err)
+			}
+			// imported files are owner-only like the configs, whatever the archive
+			// says, the config holds database passwords
+			out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(header.Mode).Perm()&^0077)
+			if err != nil {
+				return "", fmt.Errorf("error creating file: %w", err)
+			}
+			_, err = io.Copy(out, tr)
+			out.Close()
+			if err != nil {
+				return "", fmt.Errorf("error extracting file: %w", err)
+			}
+		}
+	}
+
+	if project == "" {
+		return "", fmt.Errorf("archive is empty")
+	}
+
+	if name != "" {
+		if err := updateConfig(projectDir, "PROJECT_ID", project); err != nil {
+			return "", err
+		}
+	}
+
+	return project, nil
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const DefaultEngineURL = "http://localhost:5050"
+const graphqlPath = "/secured/graphql"
+
+func init() {
+	queryCmd.Flags().StringP("file", "f", "", "File containing the GraphQL operation")
+	queryCmd.Flags().String("query", "", "GraphQL operation to run")
+	queryCmd.Flags().StringArray("var", nil, "Variable as key=value, JSON values are decoded (repeatable)")
+	queryCmd.Flags().String("key", "", "API key (default API_KEY from the project config)")
+	queryCmd.Flags().String("url", "", "Engine URL (default ENGINE_URL from the project config or "+DefaultEngineURL+")")
+}
+
+var queryCmd = &cobra.Command{
+	Use:   "query",
+	Short: "Run a GraphQL query against the project engine",
+	Long:  `Post a GraphQL query or mutation to the engine of the specified project and print the JSON result.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		project, _ := cmd.Flags().GetString("project")
+		file, _ := cmd.Flags().GetString("file")
+		query, _ := cmd.Flags().GetString("query")
+		vars, _ := cmd.Flags().GetStringArray("var")
+		key, _ := cmd.Flags().GetString("key")
+		url, _ := cmd.Flags().GetString("url")
+
+		if project == "" {
+			logError("Error: --project is required")
+			return
+		}
+
+		if file != "" {
+			content, err := os.ReadFile(file)
+			if err != nil {
+				logError("Error reading query file:", err)
+				return
+			}
+			query = string(content)
+		}
+		if strings.TrimSpace(query) == "" {
+			logError("Error: --file or --query is required")
+			return
+		}
+
+		variables, err := parseGraphQLVariables(vars)
+		if err != nil {
+			logError("Error:", err)
+			return
+		}
+
+		endpoint, err := getEngineEndpoint(project, url, key)
+		if err != nil {
+			logError("Error:", err)
+			return
+		}
+
+		result, err := runGraphQL(endpoint, query, variables)
+		if err != nil {
+			logError("Error running query:", err)
+			return
+		}
+
+		var out bytes.Buffer
+		if err := json.Indent(&out, result, "", "  "); err != nil {
+			fmt.Println(string(result))
+		} else {
+			fmt.Println(out.String())
+		}
+
+		var response struct {
+			Errors []json.RawMessage `json:"errors"`
+		}
+		if err := json.Unmarshal(result, &response); err == nil && len(response.Errors) > 0 {
+			exitCode = 1
+		}
+	},
+}
+
+// engineEndpoint is where and how to reach the engine of a project
+type engineEndpoint struct {
+	URL string
+	Key string
+
+	// ClientCert and ClientKey are used for engines behind mutual TLS
+	ClientCert string
+	ClientKey  string
+
+	// Headers are sent with every request, e.g. Cloudflare Access tokens
+	Headers map[string]string
+}
+
+// engineHeaderPrefix marks config keys that are sent as request headers,
+// ENGINE_HEADER_CF_ACCESS_CLIENT_ID becomes the Cf-Access-Client-Id header
+const engineHeaderPrefix = "ENGINE_HEADER_"
+
+// do sends the request to the engine with the api key, the extra headers
+// and the client certificate of the project
+func (e *engineEndpoint) do(req *http.Request, timeout time.Duration) (*http.Response, error) {
+	var tlsConfig *tls.Config
+	if e.ClientCert != "" || e.ClientKey != "" {
+		cert, err := tls.LoadX509KeyPair(e.ClientCert, e.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("error loading client certificate: %w", err)
+		}
+		tlsConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+	}
+
+	for name, value := range e.Headers {
+		req.Header.Set(name, value)
+	}
+	if e.Key != "" {
+		req.Header.Set("Authorization", "Bearer "+e.Key)
+	}
+
+	return newHTTPClientWithTLS(timeout, tlsConfig).Do(req)
+}
+
+// getEngineEndpoint resolves the engine url and api key of the project, the
+// flag values take precedence over the project config
+func getEngineEndpoint(project, url, key string) (*engineEndpoint, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("error finding home directory: %w", err)
+	}
+	projectDir := filepath.Join(homeDir, ".apito", project)
+
+	envMap, err := getConfig(projectDir)
+	if err != nil {
+		return nil, err
+	}
+
+	if url == "" {
+		url = envMap["ENGINE_URL"]
+	}
+	if url == "" {
+		url = DefaultEngineURL
+	}
+	if key == "" {
+		key = envMap["API_KEY"]
+	}
+
+	headers := map[string]string{}
+	for k, v := range envMap {
+		if name, ok := strings.CutPrefix(k, engineHeaderPrefix); ok && name != "" {
+			headers[http.CanonicalHeaderKey(strings.ReplaceAll(name, "_", "-"))] = v
+		}
+	}
+
+	return &engineEndpoint{
+		URL:        strings.TrimRight(url, "/"),
+		Key:        key,
+		ClientCert: envMap["ENGINE_CLIENT_CERT"],
+		ClientKey:  envMap["ENGINE_CLIENT_KEY"],
+		Headers:    headers,
+	}, nil
+}
+
+// runGraphQL posts the operation to the engine and returns the raw response body
+func runGraphQL(endpoint *engineEndpoint, query string, variables map[string]interface{}) (json.RawMessage, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"query":     query,
+		"variables": variables,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error encoding request: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, endpoint.URL+graphqlPath, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := endpoint.do(req, getRequestTimeout())
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to engine: %w", err)
+	}
+	defer resp.Body.Close()
+
+	result, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response: %w", err)
+	}
+
+	// a JSON body of a failed request is usually the error of the engine,
+	// e.g. for a wrong api key, so it is part of the error
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var compact bytes.Buffer
+		if json.Compact(&compact, result) == nil {
+			result = compact.Bytes()
+		}
+		return nil, fmt.Errorf("engine returned status code %d: %s", resp.StatusCode, strings.TrimSpace(string(result)))
+	}
+
+	return result, nil
+}
+
+// parseGraphQLVariables parses key=value pairs, values that are valid JSON
+// are decoded so numbers, booleans and objects keep their type
+func parseGraphQLVariables(values []string) (map[string]interface{}, error) {
+	pairs, err := parseKeyValues(values)
+	if err != nil {
+		return nil, err
+	}
+
+	variables := map[string]interface{}{}
+	for k, v := range pairs {
+		var decoded interface{}
+		if err := json.Unmarshal([]byte(v), &decoded); err == nil {
+			variables[k] = decoded
+		} else {
+			variables[k] = v
+		}
+	}
+
+	return variables, nil
+}
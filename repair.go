@@ -0,0 +1,301 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+
+	"github.com/manifoldco/promptui"
+)
+
+// requiredProjectKeys must be present in every project config
+var requiredProjectKeys = []string{"ENV", "PROJECT_ID", "PROJECT_NAME", "SYSTEM_DB_ENGINE", "PROJECT_DB_ENGINE"}
+
+// legacyConfigKeys maps keys written by older versions of the cli to their
+// current name
+var legacyConfigKeys = map[string]string{
+	"DB_ENGINE": "SYSTEM_DB_ENGINE",
+	"DB_HOST":   "SYSTEM_DB_HOST",
+	"DB_PORT":   "SYSTEM_DB_PORT",
+	"DB_USER":   "SYSTEM_DB_USER",
+	"DB_PASS":   "SYSTEM_DB_PASS",
+	"DB_NAME":   "SYSTEM_DB_NAME",
+}
+
+// projectIssue is a problem found in an existing project and how to fix it
+type projectIssue struct {
+	Description string
+	Repair      func() error
+}
+
+// repairProject checks an existing project for drift and offers to repair
+// each problem individually
+func repairProject(project, projectDir string, preset map[string]string) {
+	logInfo(Blue + fmt.Sprintf("Project %s already exists, checking it for problems...", project) + Reset)
+
+	issues, err := findProjectIssues(project, projectDir, preset)
+	if err != nil {
+		logError("Error checking project:", err)
+		return
+	}
+
+	if len(issues) == 0 {
+		fmt.Println(Green + "No problems found, the project is up to date." + Reset)
+		return
+	}
+
+	for _, issue := range issues {
+		fmt.Println(Yellow + "- " + issue.Description + Reset)
+		if !confirmSensitiveOperation("Repair this", riskNormal) {
+			continue
+		}
+		if err := issue.Repair(); err != nil {
+			logError("Error repairing:", err)
+			continue
+		}
+		fmt.Println(Green + "  repaired" + Reset)
+	}
+}
+
+func findProjectIssues(project, projectDir string, preset map[string]string) ([]projectIssue, error) {
+	envMap, err := getConfig(projectDir)
+	configMissing := errors.Is(err, fs.ErrNotExist)
+	if configMissing {
+		envMap = map[string]string{}
+	} else if err != nil {
+		return nil, err
+	}
+
+	var issues []projectIssue
+
+	var legacyKeys []string
+	for oldKey := range legacyConfigKeys {
+		legacyKeys = append(legacyKeys, oldKey)
+	}
+	sort.Strings(legacyKeys)
+
+	for _, oldKey := range legacyKeys {
+		value, ok := envMap[oldKey]
+		if !ok {
+			continue
+		}
+		oldKey, newKey := oldKey, legacyConfigKeys[oldKey]
+		issues = append(issues, projectIssue{
+			Description: fmt.Sprintf("legacy config key %s should be %s", oldKey, newKey),
+			Repair: func() error {
+				envMap, err := getConfig(projectDir)
+				if err != nil {
+					return err
+				}
+				if _, exists := envMap[newKey]; !exists {
+					envMap[newKey] = value
+				}
+				delete(envMap, oldKey)
+				return saveConfig(projectDir, envMap)
+			},
+		})
+	}
+
+	var missing []string
+	for _, key := range requiredProjectKeys {
+		if envMap[key] == "" && envMap[legacyKeyFor(key)] == "" {
+			missing = append(missing, key)
+		}
+	}
+	for _, prefix := range []string{"SYSTEM", "PROJECT"} {
+		switch envMap[prefix+"_DB_ENGINE"] {
+		case "postgres", "mysql", "mariadb":
+			for _, field := range []string{"HOST", "PORT", "USER", "NAME"} {
+				if _, ok := envMap[prefix+"_DB_"+field]; !ok {
+					missing = append(missing, prefix+"_DB_"+field)
+				}
+			}
+		}
+	}
+	// updateConfig needs an existing config, a missing one is created with
+	// all the required keys at once
+	if configMissing {
+		keys := missing
+		issues = append(issues, projectIssue{
+			Description: fmt.Sprintf("config file %s is missing", ConfigFile),
+			Repair: func() error {
+				config := map[string]string{}
+				for _, key := range keys {
+					value, err := getMissingValue(project, key, preset)
+					if err != nil {
+						return err
+					}
+					config[key] = value
+				}
+				for _, prefix := range []string{"SYSTEM", "PROJECT"} {
+					switch config[prefix+"_DB_ENGINE"] {
+					case "postgres", "mysql", "mariadb":
+						for _, field := range []string{"HOST", "PORT", "USER", "NAME"} {
+							value, err := getMissingValue(project, prefix+"_DB_"+field, preset)
+							if err != nil {
+								return err
+							}
+							config[prefix+"_DB_"+field] = value
+						}
+						if pass, ok := preset[prefix+"_DB_PASS"]; ok {
+							config[prefix+"_DB_PASS"] = pass
+						}
+					}
+				}
+				return saveConfig(projectDir, config)
+			},
+		})
+		missing = nil
+	}
+	for _, key := range missing {
+		key := key
+		issues = append(issues, projectIssue{
+			Description: fmt.Sprintf("required config key %s is missing", key),
+			Repair: func() error {
+				value, err := getMissingValue(project, key, preset)
+				if err != nil {
+					return err
+				}
+				return updateConfig(projectDir, key, value)
+			},
+		})
+	}
+
+	for _, key := range []string{"ENGINE_URL", "CONSOLE_URL"} {
+		value := envMap[key]
+		if value == "" || validateURL(value) == nil {
+			continue
+		}
+		key := key
+		issues = append(issues, projectIssue{
+			Description: fmt.Sprintf("%s %q is not a valid http(s) URL", key, value),
+			Repair: func() error {
+				value, err := getMissingValue(project, key, preset)
+				if err != nil {
+					return err
+				}
+				if err := validateURL(value); err != nil {
+					return err
+				}
+				return updateConfig(projectDir, key, value)
+			},
+		})
+	}
+
+	enginePath := filepath.Join(projectDir, project)
+	if info, err := os.Stat(enginePath); os.IsNotExist(err) {
+		issues = append(issues, projectIssue{
+			Description: "engine binary is missing",
+			Repair: func() error {
+				releaseTag := envMap["ENGINE_VERSION"]
+				if releaseTag == "" {
+					latest, err := getLatestReleaseTag()
+					if err != nil {
+						return err
+					}
+					releaseTag = latest
+				}
+				return downloadAndExtractEngine(project, releaseTag, projectDir)
+			},
+		})
+	} else if err == nil && runtime.GOOS != "windows" && info.Mode().Perm()&0100 == 0 {
+		issues = append(issues, projectIssue{
+			Description: "engine binary is not executable",
+			Repair: func() error {
+				return os.Chmod(enginePath, info.Mode().Perm()|0755)
+			},
+		})
+	}
+
+	for _, dir := range projectDataDirs {
+		dataDir := filepath.Join(projectDir, dir)
+		info, err := os.Stat(dataDir)
+		if err != nil || runtime.GOOS == "windows" {
+			continue
+		}
+		if info.Mode().Perm()&0700 != 0700 {
+			issues = append(issues, projectIssue{
+				Description: fmt.Sprintf("database directory %s is not accessible by the owner (%s)", dataDir, info.Mode().Perm()),
+				Repair: func() error {
+					return filepath.Walk(dataDir, func(path string, info os.FileInfo, err error) error {
+						if err != nil {
+							return err
+						}
+						if info.IsDir() {
+							return os.Chmod(path, info.Mode().Perm()|0700)
+						}
+						return os.Chmod(path, info.Mode().Perm()|0600)
+					})
+				},
+			})
+		}
+	}
+
+	return issues, nil
+}
+
+func legacyKeyFor(key string) string {
+	for oldKey, newKey := range legacyConfigKeys {
+		if newKey == key {
+			return oldKey
+		}
+	}
+	return ""
+}
+
+// getMissingValue takes the value of a missing config key from the preset,
+// a sensible default or a prompt
+func getMissingValue(project, key string, preset map[string]string) (string, error) {
+	if value, ok := preset[key]; ok {
+		if key == "SYSTEM_DB_ENGINE" && value == "storageDb" {
+			value = "badger"
+		}
+		return value, nil
+	}
+
+	switch key {
+	case "ENV":
+		return "local", nil
+	case "PROJECT_ID":
+		return project, nil
+	}
+
+	if nonInteractive {
+		return "", fmt.Errorf("%s is required", key)
+	}
+
+	switch key {
+	case "SYSTEM_DB_ENGINE":
+		db, err := selectOption("System Database", systemDBEngines, "")
+		if db == "storageDb" {
+			db = "badger"
+		}
+		return db, err
+	case "PROJECT_DB_ENGINE":
+		return selectOption("Project Database", projectDBEngines, "")
+	}
+
+	prompt := promptui.Prompt{Label: key}
+	value, err := prompt.Run()
+	if err != nil {
+		return "", fmt.Errorf("prompt failed: %w", err)
+	}
+	return value, nil
+}
+
+// validateURL checks that value is an absolute http or https URL
+func validateURL(value string) error {
+	u, err := url.Parse(value)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%q is not an http(s) URL", value)
+	}
+	return nil
+}
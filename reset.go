@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/client"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	resetCmd.Flags().Bool("config", false, "Remove the project config")
+	resetCmd.Flags().Bool("data", false, "Remove the project databases")
+	resetCmd.Flags().Bool("binaries", false, "Remove the downloaded engine and build archives")
+	resetCmd.Flags().Bool("docker", false, "Remove the docker image and containers built for the project")
+	resetCmd.Flags().Bool("all", false, "Remove everything, without --project the whole ~/.apito directory")
+}
+
+var resetCmd = &cobra.Command{
+	Annotations: map[string]string{lockAnnotation: "true", explicitProjectAnnotation: "true"},
+	Use:         "reset",
+	Short:       "Remove project config, data, binaries or docker images",
+	Long: `Stop the engine and remove the selected parts of a project. Every scope asks for confirmation unless --force is set.
+Without --project, --all removes the whole ~/.apito directory including the login and every project.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		project, _ := cmd.Flags().GetString("project")
+		all, _ := cmd.Flags().GetBool("all")
+
+		scopes := map[string]bool{}
+		for _, scope := range []string{"config", "data", "binaries", "docker"} {
+			scopes[scope], _ = cmd.Flags().GetBool(scope)
+			if all {
+				scopes[scope] = true
+			}
+		}
+		if !scopes["config"] && !scopes["data"] && !scopes["binaries"] && !scopes["docker"] {
+			logError("Error: select at least one of --config, --data, --binaries, --docker or --all")
+			return
+		}
+
+		apitoDir, err := getApitoDir()
+		if err != nil {
+			logError("Error finding home directory:", err)
+			return
+		}
+
+		if project == "" {
+			if !all {
+				logError("Error: --project is required unless --all is set")
+				return
+			}
+			resetAll(apitoDir)
+			return
+		}
+
+		projectDir := filepath.Join(apitoDir, project)
+		if _, err := os.Stat(projectDir); os.IsNotExist(err) {
+			logError("Error: project", project, "does not exist")
+			return
+		}
+
+		// every scope is confirmed before the engine is stopped, declining
+		// them all leaves the project as it was
+		removeAll := all && confirmSensitiveOperation(fmt.Sprintf("Remove %s", projectDir), riskDestructive)
+		confirmed := map[string]bool{
+			"docker":   scopes["docker"] && confirmSensitiveOperation(fmt.Sprintf("Remove the docker image and containers of %s", project), riskDestructive),
+			"binaries": !removeAll && scopes["binaries"] && confirmSensitiveOperation(fmt.Sprintf("Remove the engine and build archives of %s", project), riskDestructive),
+			"data":     !removeAll && scopes["data"] && confirmSensitiveOperation(fmt.Sprintf("Remove all databases of %s", project), riskDestructive),
+			"config":   !all && scopes["config"] && confirmSensitiveOperation(fmt.Sprintf("Remove the config of %s", project), riskDestructive),
+		}
+		if !removeAll && !confirmed["docker"] && !confirmed["binaries"] && !confirmed["data"] && !confirmed["config"] {
+			return
+		}
+
+		stopRunningEngine(project, projectDir)
+
+		if confirmed["docker"] {
+			if err := removeProjectDocker(project); err != nil {
+				logError("Error removing docker image:", err)
+			}
+		}
+		if confirmed["binaries"] {
+			removePaths(projectBinaryPaths(apitoDir, project))
+		}
+		if confirmed["data"] {
+			var paths []string
+			for _, dir := range projectDataDirs {
+				paths = append(paths, filepath.Join(projectDir, dir))
+			}
+			removePaths(paths)
+		}
+		if removeAll {
+			removePaths([]string{projectDir})
+		} else if confirmed["config"] {
+			removePaths([]string{filepath.Join(projectDir, ConfigFile)})
+		}
+	},
+}
+
+// resetAll stops every running engine and removes ~/.apito
+func resetAll(apitoDir string) {
+	entries, err := os.ReadDir(apitoDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			fmt.Println("Nothing to remove")
+			return
+		}
+		logError("Error reading", apitoDir+":", err)
+		return
+	}
+
+	if !confirmSensitiveOperation(fmt.Sprintf("Stop all engines and remove %s with every project and the login", apitoDir), riskDestructive) {
+		return
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			stopRunningEngine(entry.Name(), filepath.Join(apitoDir, entry.Name()))
+		}
+	}
+
+	removePaths([]string{apitoDir})
+}
+
+func stopRunningEngine(project, projectDir string) {
+	envMap, err := getConfig(projectDir)
+	if err != nil {
+		return
+	}
+	pid, err := strconv.Atoi(envMap["ENGINE_PID"])
+	if err == nil && isProcessRunning(pid) {
+		logInfo("Stopping the engine of", project)
+		stopEngine(project, engineStopTimeout)
+	}
+}
+
+// projectBinaryPaths returns the engine binary, downloaded release archives
+// and the archive created by `apito build zip`
+func projectBinaryPaths(apitoDir, project string) []string {
+	projectDir := filepath.Join(apitoDir, project)
+	paths := []string{
+		filepath.Join(projectDir, project),
+		filepath.Join(apitoDir, project+".zip"),
+	}
+	archives, _ := filepath.Glob(filepath.Join(projectDir, "engine-*.zip"))
+	return append(paths, archives...)
+}
+
+func removePaths(paths []string) {
+	for _, path := range paths {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			continue
+		}
+		if dryRunSkip("remove %s", path) {
+			continue
+		}
+		if err := os.RemoveAll(path); err != nil {
+			logError("Error removing", path+":", err)
+			continue
+		}
+		fmt.Println(Green+"Removed"+Reset, path)
+	}
+}
+
+// removeProjectDocker removes the image built by `apito build docker` and
+// the containers created from it
+func removeProjectDocker(project string) error {
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		return fmt.Errorf("error creating Docker client: %w", err)
+	}
+	defer cli.Close()
+
+	ctx := context.Background()
+	imageName := fmt.Sprintf("apito.io/projects/%s", strings.ToLower(project))
+
+	containers, err := cli.ContainerList(ctx, container.ListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("ancestor", imageName)),
+	})
+	if err != nil {
+		return fmt.Errorf("error listing containers: %w", err)
+	}
+	for _, c := range containers {
+		if dryRunSkip("run docker rm --force --volumes %s", c.ID[:12]) {
+			continue
+		}
+		if err := cli.ContainerRemove(ctx, c.ID, container.RemoveOptions{Force: true, RemoveVolumes: true}); err != nil {
+			return fmt.Errorf("error removing container %s: %w", c.ID[:12], err)
+		}
+		fmt.Println(Green+"Removed container"+Reset, c.ID[:12])
+	}
+
+	if dryRunSkip("run docker rmi %s", imageName) {
+		return nil
+	}
+	if _, err := cli.ImageRemove(ctx, imageName, image.RemoveOptions{PruneChildren: true}); err != nil {
+		if client.IsErrNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	fmt.Println(Green+"Removed image"+Reset, imageName)
+	return nil
+}
@@ -2,89 +2,142 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/eiannone/keyboard"
 	"github.com/spf13/cobra"
 )
 
+// engineStopTimeout is how long the engine gets to shut down after SIGTERM
+// before it is killed
+const engineStopTimeout = 30 * time.Second
+
+// restart backoff of apito run --supervise, the backoff and the restart
+// count are reset once the engine ran for restartStableAfter
+const (
+	restartBackoffMin  = time.Second
+	restartBackoffMax  = time.Minute
+	restartStableAfter = 5 * time.Minute
+)
+
+func init() {
+	runCmd.Flags().Bool("skip-update-check", false, "Do not check for new engine and console releases")
+	runCmd.Flags().StringArray("engine-env", nil, "Extra environment variable for the engine as KEY=VALUE, only for this run (repeatable)")
+	runCmd.Flags().StringArray("engine-arg", nil, "Extra command line argument for the engine, only for this run (repeatable)")
+	runCmd.Flags().Bool("supervise", false, "Restart the engine with backoff when it exits on its own")
+	runCmd.Flags().Int("max-restarts", 5, "With --supervise, give up after this many restarts in a row")
+}
+
 var runCmd = &cobra.Command{
-	Use:   "run",
-	Short: "Run the engine for the specified project",
-	Long:  `Run the engine binary located at ~/.apito/<project>/<project>`,
+	Annotations: mutatingAnnotations,
+	Use:         "run",
+	Short:       "Run the engine for the specified project",
+	Long:        `Run the engine binary located at ~/.apito/<project>/<project>`,
 	Run: func(cmd *cobra.Command, args []string) {
 		project, _ := cmd.Flags().GetString("project")
 		if project == "" {
-			fmt.Println("Error: --project is required")
+			logError("Error: --project is required")
 			return
 		}
-		runEngine(project)
+		engineEnv, _ := cmd.Flags().GetStringArray("engine-env")
+		engineArgs, _ := cmd.Flags().GetStringArray("engine-arg")
+		supervise, _ := cmd.Flags().GetBool("supervise")
+		maxRestarts, _ := cmd.Flags().GetInt("max-restarts")
+
+		if _, err := parseKeyValues(engineEnv); err != nil {
+			logError("Error:", err)
+			return
+		}
+
+		if skip, _ := cmd.Flags().GetBool("skip-update-check"); !skip {
+			checkForComponentUpdates(project)
+		}
+		if !supervise {
+			maxRestarts = 0
+		}
+		runEngine(project, engineEnv, engineArgs, maxRestarts)
 	},
 }
 
-func runEngine(project string) {
+// runEngine starts the engine of the project, env and args are added to the
+// engine process without changing the project config
+func runEngine(project string, env, args []string, maxRestarts int) {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
-		fmt.Println("Error finding home directory:", err)
+		logError("Error finding home directory:", err)
 		return
 	}
 	projectDir := filepath.Join(homeDir, ".apito", project)
+	if dryRunSkip("start %s", strings.Join(append([]string{filepath.Join(projectDir, project)}, args...), " ")) {
+		return
+	}
 
 	ctx := context.Background()
 
-	err = run(ctx, projectDir, project)
+	err = run(ctx, projectDir, project, env, args, maxRestarts)
 	if err != nil {
-		fmt.Println("Error starting engine:", err)
+		logError("Error running engine:", err)
 		return
 	}
 
-	fmt.Println("Engine started with PID:")
+	fmt.Println("Engine stopped")
 }
 
-// #todo better handling the process termination process
-func run(ctx context.Context, projectDir, projectName string) error {
-
-	enginePath := filepath.Join(projectDir, projectName)
-
+// run starts the engine and waits until it exits. Stopping it from the
+// keyboard sends SIGTERM so it can flush its databases, it is only killed
+// when it is still running after engineStopTimeout. With maxRestarts above
+// zero an engine that exits on its own is started again with backoff.
+func run(ctx context.Context, projectDir, projectName string, env, args []string, maxRestarts int) error {
 	ctx, cancel := context.WithCancel(ctx)
-	cmd := exec.CommandContext(ctx, "sh", "-c", enginePath)
+	defer cancel()
 
-	cmd.SysProcAttr = &syscall.SysProcAttr{
-		Setpgid: true,
+	// Set the output of the command, it is also kept for apito logs
+	var stdout, stderr io.Writer = os.Stdout, os.Stderr
+	var engineLog *timestampWriter
+	logFile, err := os.OpenFile(filepath.Join(projectDir, EngineLogFile), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		logWarn("Engine output is not saved:", err)
+	} else {
+		defer logFile.Close()
+		engineLog = &timestampWriter{w: logFile}
+		defer engineLog.Flush()
+		stdout = io.MultiWriter(os.Stdout, engineLog)
+		stderr = io.MultiWriter(os.Stderr, engineLog)
 	}
 
-	/*cmd.Cancel = func() error {
-		return nil
-	}*/
-
-	// Set the output of the command
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-
-	fmt.Println("Starting app :", projectName, cmd.String())
+	// a stop of an earlier run must not end this one
+	os.Remove(filepath.Join(projectDir, EngineStopFile))
 
-	err := cmd.Start()
+	logInfo("Starting app :", projectName)
+	cmd, err := startEngineProcess(ctx, filepath.Join(projectDir, projectName), env, args, stdout, stderr)
 	if err != nil {
-		return fmt.Errorf("failed to start the app: %w", err)
+		return err
 	}
 
 	// Save the PID to the .env file
-	pid := cmd.Process.Pid
-	err = updateConfig(projectDir, "ENGINE_PID", strconv.Itoa(pid))
+	err = updateConfig(projectDir, "ENGINE_PID", strconv.Itoa(cmd.Process.Pid))
 	if err != nil {
 		return err
 	}
 
+	// the engine runs in the foreground until it is stopped, other commands
+	// must not wait for it
+	unlockApitoDir()
+
 	fmt.Println("Press `Ctrl+T` or `q` to stop the engine...")
 
 	// Start listening for keyboard inputs
 	if err = keyboard.Open(); err != nil {
-		return err // Execution may end after this instruction without calling 'cancel' function
+		return err
 	}
 	defer keyboard.Close()
 
@@ -92,7 +145,9 @@ func run(ctx context.Context, projectDir, projectName string) error {
 		for {
 			char, key, err := keyboard.GetKey()
 			if err != nil {
-				fmt.Printf("Error reading input: %v\n", err)
+				if ctx.Err() == nil {
+					logError("Error reading input:", err)
+				}
 				return
 			}
 
@@ -112,19 +167,147 @@ func run(ctx context.Context, projectDir, projectName string) error {
 		}
 	}()
 
-	select {
-	case <-ctx.Done():
-		if ctx.Err() != nil {
-			fmt.Print(ctx.Err())
-		} else {
-			fmt.Printf("Server process exited gracefully")
+	restarts := 0
+	backoff := restartBackoffMin
+	for {
+		started := time.Now()
+		stopped, err := waitEngineProcess(ctx, cmd)
+		if stopped || maxRestarts <= 0 || engineStopRequested(projectDir) {
+			cancel()
+			clearEnginePID(projectDir)
+			return err
+		}
+
+		// a run that lasted a while was not a crash loop, start counting again
+		if time.Since(started) > restartStableAfter {
+			restarts = 0
+			backoff = restartBackoffMin
+		}
+		if restarts >= maxRestarts {
+			cancel()
+			clearEnginePID(projectDir)
+			return fmt.Errorf("the engine exited %d times in a row, giving up: %v", restarts+1, err)
+		}
+		restarts++
+
+		reason := "exit status 0"
+		if err != nil {
+			reason = err.Error()
+		}
+		event := fmt.Sprintf("engine exited (%s), restarting in %s (%d/%d)", reason, backoff, restarts, maxRestarts)
+		logWarn(event)
+		if engineLog != nil {
+			fmt.Fprintln(engineLog, "[apito] "+event)
+		}
+
+		select {
+		case <-ctx.Done():
+			clearEnginePID(projectDir)
+			return nil
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, restartBackoffMax)
+
+		cmd, err = restartEngineProcess(ctx, projectDir, projectName, env, args, stdout, stderr)
+		if cmd == nil || err != nil {
+			cancel()
+			clearEnginePID(projectDir)
+			return err
 		}
 	}
+}
 
-	err = cmd.Wait()
+// restartEngineProcess starts the engine again and saves its PID. It holds
+// the lock meanwhile, so apito stop either sees the new PID or has recorded
+// the stop before, then the engine is not started and cmd is nil.
+func restartEngineProcess(ctx context.Context, projectDir, projectName string, env, args []string, stdout, stderr io.Writer) (*exec.Cmd, error) {
+	if err := lockApitoDir(true); err != nil {
+		return nil, err
+	}
+	defer unlockApitoDir()
+
+	if engineStopRequested(projectDir) {
+		return nil, nil
+	}
+	cmd, err := startEngineProcess(ctx, filepath.Join(projectDir, projectName), env, args, stdout, stderr)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if err := updateConfig(projectDir, "ENGINE_PID", strconv.Itoa(cmd.Process.Pid)); err != nil {
+		logWarn("Error saving ENGINE_PID:", err)
+	}
+	return cmd, nil
+}
+
+// startEngineProcess starts the engine binary in its own process group,
+// cancelling ctx asks it to shut down with SIGTERM
+func startEngineProcess(ctx context.Context, enginePath string, env, args []string, stdout, stderr io.Writer) (*exec.Cmd, error) {
+	cmd := exec.CommandContext(ctx, enginePath, args...)
+	cmd.Env = append(os.Environ(), env...)
+
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Setpgid: true,
+	}
+
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(syscall.SIGTERM)
+	}
+	cmd.WaitDelay = engineStopTimeout
+
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	logDebug("Executing", cmd.String())
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start the app: %w", err)
+	}
+	return cmd, nil
+}
+
+// waitEngineProcess waits for the engine to exit. stopped reports whether
+// it was stopped on purpose, from the keyboard or with SIGTERM, in which
+// case err is only set for unexpected failures. A SIGKILL that was not sent
+// by us, e.g. from the OOM killer, counts as a crash.
+func waitEngineProcess(ctx context.Context, cmd *exec.Cmd) (stopped bool, err error) {
+	exited := make(chan error, 1)
+	go func() {
+		exited <- cmd.Wait()
+	}()
+
+	stopRequested := false
+	select {
+	case <-ctx.Done():
+		stopRequested = true
+		logInfo(fmt.Sprintf("Waiting up to %s for the engine to shut down...", engineStopTimeout))
+		err = <-exited
+	case err = <-exited:
 	}
 
-	return nil
+	if errors.Is(err, exec.ErrWaitDelay) {
+		// the engine exited but a child process still held its output
+		return true, nil
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		status, _ := exitErr.Sys().(syscall.WaitStatus)
+		switch {
+		case status.Signaled() && status.Signal() == syscall.SIGKILL && stopRequested:
+			logWarn(fmt.Sprintf("The engine did not shut down within %s and was killed", engineStopTimeout))
+			return true, nil
+		case status.Signaled() && status.Signal() == syscall.SIGTERM:
+			return true, nil
+		}
+	}
+	return stopRequested, err
+}
+
+// clearEnginePID removes the PID of the exited engine from the config
+func clearEnginePID(projectDir string) {
+	if err := lockApitoDir(true); err != nil {
+		return
+	}
+	defer unlockApitoDir()
+	if err := updateConfig(projectDir, "ENGINE_PID", ""); err != nil {
+		logWarn("Error clearing ENGINE_PID:", err)
+	}
 }
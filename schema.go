@@ -0,0 +1,348 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	schemaExportCmd.Flags().StringP("out", "o", "", "Output file (default stdout)")
+	schemaExportCmd.Flags().String("format", "sdl", "Output format: sdl or json (introspection result)")
+	schemaExportCmd.Flags().String("key", "", "API key (default API_KEY from the project config)")
+	schemaExportCmd.Flags().String("url", "", "Engine URL (default ENGINE_URL from the project config or "+DefaultEngineURL+")")
+
+	schemaCmd.AddCommand(schemaExportCmd)
+}
+
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Work with the GraphQL schema of a project",
+}
+
+var schemaExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the GraphQL schema of the project engine",
+	Long:  `Fetch the GraphQL schema from the engine of the project with an introspection query and write it as SDL or as the introspection JSON, e.g. for client code generation.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		project, _ := cmd.Flags().GetString("project")
+		out, _ := cmd.Flags().GetString("out")
+		format, _ := cmd.Flags().GetString("format")
+		key, _ := cmd.Flags().GetString("key")
+		url, _ := cmd.Flags().GetString("url")
+
+		if project == "" {
+			logError("Error: --project is required")
+			return
+		}
+		if format != "sdl" && format != "json" {
+			logError("Error: --format must be sdl or json")
+			return
+		}
+
+		endpoint, err := getEngineEndpoint(project, url, key)
+		if err != nil {
+			logError("Error:", err)
+			return
+		}
+
+		result, err := runGraphQL(endpoint, introspectionQuery, nil)
+		if err != nil {
+			logError("Error fetching schema:", err)
+			return
+		}
+
+		var response struct {
+			Data   json.RawMessage `json:"data"`
+			Errors []struct {
+				Message string `json:"message"`
+			} `json:"errors"`
+		}
+		if err := json.Unmarshal(result, &response); err != nil {
+			logError("Error decoding response:", err)
+			return
+		}
+		if len(response.Errors) > 0 {
+			logError("Error fetching schema:", response.Errors[0].Message)
+			return
+		}
+
+		var content []byte
+		if format == "json" {
+			var buf bytes.Buffer
+			if err := json.Indent(&buf, result, "", "  "); err != nil {
+				logError("Error formatting schema:", err)
+				return
+			}
+			content = append(buf.Bytes(), '\n')
+		} else {
+			var data struct {
+				Schema introspectionSchema `json:"__schema"`
+			}
+			if err := json.Unmarshal(response.Data, &data); err != nil {
+				logError("Error decoding schema:", err)
+				return
+			}
+			content = []byte(printSchemaSDL(data.Schema))
+		}
+
+		if out == "" {
+			os.Stdout.Write(content)
+			return
+		}
+		if err := os.WriteFile(out, content, 0644); err != nil {
+			logError("Error writing schema:", err)
+			return
+		}
+		logInfo(Green+"Schema written to"+Reset, out)
+	},
+}
+
+const introspectionQuery = `query IntrospectionQuery {
+  __schema {
+    queryType { name }
+    mutationType { name }
+    subscriptionType { name }
+    types { ...FullType }
+  }
+}
+
+fragment FullType on __Type {
+  kind
+  name
+  description
+  fields(includeDeprecated: true) {
+    name
+    description
+    args { ...InputValue }
+    type { ...TypeRef }
+    isDeprecated
+    deprecationReason
+  }
+  inputFields { ...InputValue }
+  interfaces { ...TypeRef }
+  enumValues(includeDeprecated: true) {
+    name
+    description
+    isDeprecated
+    deprecationReason
+  }
+  possibleTypes { ...TypeRef }
+}
+
+fragment InputValue on __InputValue {
+  name
+  description
+  type { ...TypeRef }
+  defaultValue
+}
+
+fragment TypeRef on __Type {
+  kind
+  name
+  ofType { kind name ofType { kind name ofType { kind name ofType { kind name ofType { kind name ofType { kind name ofType { kind name } } } } } } }
+}`
+
+type introspectionSchema struct {
+	QueryType        *introspectionName  `json:"queryType"`
+	MutationType     *introspectionName  `json:"mutationType"`
+	SubscriptionType *introspectionName  `json:"subscriptionType"`
+	Types            []introspectionType `json:"types"`
+}
+
+type introspectionName struct {
+	Name string `json:"name"`
+}
+
+type introspectionType struct {
+	Kind          string                 `json:"kind"`
+	Name          string                 `json:"name"`
+	Description   string                 `json:"description"`
+	Fields        []introspectionField   `json:"fields"`
+	InputFields   []introspectionInput   `json:"inputFields"`
+	Interfaces    []introspectionTypeRef `json:"interfaces"`
+	EnumValues    []introspectionEnum    `json:"enumValues"`
+	PossibleTypes []introspectionTypeRef `json:"possibleTypes"`
+}
+
+type introspectionField struct {
+	Name              string               `json:"name"`
+	Description       string               `json:"description"`
+	Args              []introspectionInput `json:"args"`
+	Type              introspectionTypeRef `json:"type"`
+	IsDeprecated      bool                 `json:"isDeprecated"`
+	DeprecationReason string               `json:"deprecationReason"`
+}
+
+type introspectionInput struct {
+	Name         string               `json:"name"`
+	Description  string               `json:"description"`
+	Type         introspectionTypeRef `json:"type"`
+	DefaultValue *string              `json:"defaultValue"`
+}
+
+type introspectionEnum struct {
+	Name              string `json:"name"`
+	Description       string `json:"description"`
+	IsDeprecated      bool   `json:"isDeprecated"`
+	DeprecationReason string `json:"deprecationReason"`
+}
+
+type introspectionTypeRef struct {
+	Kind   string                `json:"kind"`
+	Name   string                `json:"name"`
+	OfType *introspectionTypeRef `json:"ofType"`
+}
+
+func (t introspectionTypeRef) String() string {
+	switch t.Kind {
+	case "NON_NULL":
+		if t.OfType != nil {
+			return t.OfType.String() + "!"
+		}
+	case "LIST":
+		if t.OfType != nil {
+			return "[" + t.OfType.String() + "]"
+		}
+	}
+	return t.Name
+}
+
+var builtinScalars = []string{"String", "Int", "Float", "Boolean", "ID"}
+
+// printSchemaSDL turns an introspection result into schema definition
+// language, built-in scalars and introspection types are left out
+func printSchemaSDL(schema introspectionSchema) string {
+	var sdl strings.Builder
+
+	roots := []struct {
+		operation string
+		name      *introspectionName
+		fallback  string
+	}{
+		{"query", schema.QueryType, "Query"},
+		{"mutation", schema.MutationType, "Mutation"},
+		{"subscription", schema.SubscriptionType, "Subscription"},
+	}
+	var schemaDef []string
+	custom := false
+	for _, root := range roots {
+		if root.name == nil {
+			continue
+		}
+		schemaDef = append(schemaDef, fmt.Sprintf("  %s: %s", root.operation, root.name.Name))
+		if root.name.Name != root.fallback {
+			custom = true
+		}
+	}
+	if custom {
+		sdl.WriteString("schema {\n" + strings.Join(schemaDef, "\n") + "\n}\n\n")
+	}
+
+	types := append([]introspectionType(nil), schema.Types...)
+	sort.Slice(types, func(i, j int) bool { return types[i].Name < types[j].Name })
+
+	for _, t := range types {
+		if strings.HasPrefix(t.Name, "__") || (t.Kind == "SCALAR" && ArrayContains(builtinScalars, t.Name)) {
+			continue
+		}
+
+		writeDescription(&sdl, t.Description, "")
+		switch t.Kind {
+		case "SCALAR":
+			sdl.WriteString("scalar " + t.Name + "\n")
+		case "OBJECT", "INTERFACE":
+			keyword := "type"
+			if t.Kind == "INTERFACE" {
+				keyword = "interface"
+			}
+			sdl.WriteString(keyword + " " + t.Name)
+			if len(t.Interfaces) > 0 {
+				var names []string
+				for _, i := range t.Interfaces {
+					names = append(names, i.Name)
+				}
+				sdl.WriteString(" implements " + strings.Join(names, " & "))
+			}
+			sdl.WriteString(" {\n")
+			for _, f := range t.Fields {
+				writeDescription(&sdl, f.Description, "  ")
+				sdl.WriteString("  " + f.Name + formatArgs(f.Args) + ": " + f.Type.String() + formatDeprecation(f.IsDeprecated, f.DeprecationReason) + "\n")
+			}
+			sdl.WriteString("}\n")
+		case "UNION":
+			var names []string
+			for _, p := range t.PossibleTypes {
+				names = append(names, p.Name)
+			}
+			sdl.WriteString("union " + t.Name + " = " + strings.Join(names, " | ") + "\n")
+		case "ENUM":
+			sdl.WriteString("enum " + t.Name + " {\n")
+			for _, v := range t.EnumValues {
+				writeDescription(&sdl, v.Description, "  ")
+				sdl.WriteString("  " + v.Name + formatDeprecation(v.IsDeprecated, v.DeprecationReason) + "\n")
+			}
+			sdl.WriteString("}\n")
+		case "INPUT_OBJECT":
+			sdl.WriteString("input " + t.Name + " {\n")
+			for _, f := range t.InputFields {
+				writeDescription(&sdl, f.Description, "  ")
+				sdl.WriteString("  " + formatInputValue(f) + "\n")
+			}
+			sdl.WriteString("}\n")
+		}
+		sdl.WriteString("\n")
+	}
+
+	return strings.TrimRight(sdl.String(), "\n") + "\n"
+}
+
+func writeDescription(sdl *strings.Builder, description, indent string) {
+	if description == "" {
+		return
+	}
+	if !strings.Contains(description, "\n") && !strings.Contains(description, `"`) {
+		sdl.WriteString(indent + `"` + description + `"` + "\n")
+		return
+	}
+	sdl.WriteString(indent + `"""` + "\n")
+	for _, line := range strings.Split(strings.ReplaceAll(description, `"""`, `\"""`), "\n") {
+		sdl.WriteString(indent + line + "\n")
+	}
+	sdl.WriteString(indent + `"""` + "\n")
+}
+
+func formatArgs(args []introspectionInput) string {
+	if len(args) == 0 {
+		return ""
+	}
+	var parts []string
+	for _, a := range args {
+		parts = append(parts, formatInputValue(a))
+	}
+	return "(" + strings.Join(parts, ", ") + ")"
+}
+
+func formatInputValue(v introspectionInput) string {
+	s := v.Name + ": " + v.Type.String()
+	if v.DefaultValue != nil {
+		s += " = " + *v.DefaultValue
+	}
+	return s
+}
+
+func formatDeprecation(deprecated bool, reason string) string {
+	if !deprecated {
+		return ""
+	}
+	if reason == "" || reason == "No longer supported" {
+		return " @deprecated"
+	}
+	reasonJSON, _ := json.Marshal(reason)
+	return " @deprecated(reason: " + string(reasonJSON) + ")"
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+// showSecrets prints config secrets in full instead of masked, the debug log
+// is always masked
+var showSecrets bool
+
+// sensitiveKeySuffixes mark config keys whose values are secrets, e.g.
+// SYSTEM_DB_PASS or BRANKA_KEY
+var sensitiveKeySuffixes = []string{"PASS", "PASSWORD", "KEY", "SECRET", "TOKEN"}
+
+// sensitiveAssignmentPattern finds KEY=value pairs of secrets in free text,
+// such as executed commands or error messages
+var sensitiveAssignmentPattern = regexp.MustCompile(`(?i)\b([a-z0-9_]*(?:pass|password|key|secret|token))=([^\s"']+)`)
+
+const maskedValue = "********"
+
+// isSensitiveKey reports whether the config key holds a secret
+func isSensitiveKey(key string) bool {
+	key = strings.ToUpper(key)
+	for _, suffix := range sensitiveKeySuffixes {
+		if key == suffix || strings.HasSuffix(key, "_"+suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// maskSensitiveValue returns the value of a config key for printing, secrets
+// are masked unless --show-secrets is set
+func maskSensitiveValue(key, value string) string {
+	if showSecrets || value == "" || !isSensitiveKey(key) {
+		return value
+	}
+	return maskedValue
+}
+
+// redactSecrets masks the values of KEY=value secrets in s
+func redactSecrets(s string) string {
+	return sensitiveAssignmentPattern.ReplaceAllString(s, "$1="+maskedValue)
+}
+
+// redactOutput is redactSecrets for terminal output, which --show-secrets
+// leaves as is
+func redactOutput(s string) string {
+	if showSecrets {
+		return s
+	}
+	return redactSecrets(s)
+}
+
+// generated passwords only use letters and digits, so they need no quoting
+// in SQL, URLs or .env files
+const (
+	passwordCharset       = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+	DefaultPasswordLength = 32
+)
+
+// generatePassword returns a random password of length characters from
+// charset, read from crypto/rand
+func generatePassword(length int, charset string) (string, error) {
+	if length <= 0 || charset == "" {
+		return "", fmt.Errorf("invalid password length %d", length)
+	}
+	limit := big.NewInt(int64(len(charset)))
+	b := make([]byte, length)
+	for i := range b {
+		n, err := rand.Int(rand.Reader, limit)
+		if err != nil {
+			return "", fmt.Errorf("error generating password: %w", err)
+		}
+		b[i] = charset[n.Int64()]
+	}
+	return string(b), nil
+}
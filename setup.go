@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SetupFile describes a project for unattended provisioning with
+// `apito create project --from-file setup.yml`
+type SetupFile struct {
+	Name          string            `yaml:"name"`
+	FullName      string            `yaml:"full_name"`
+	EngineVersion string            `yaml:"engine_version"`
+	SystemDB      SetupDatabase     `yaml:"system_db"`
+	ProjectDB     SetupDatabase     `yaml:"project_db"`
+	Env           map[string]string `yaml:"env"`
+}
+
+type SetupDatabase struct {
+	Engine string `yaml:"engine"`
+	Host   string `yaml:"host"`
+	Port   string `yaml:"port"`
+	User   string `yaml:"user"`
+	Pass   string `yaml:"pass"`
+	Name   string `yaml:"name"`
+}
+
+// nonInteractive makes every prompt fail instead of asking, it is set when
+// the project is created from a setup file
+var nonInteractive bool
+
+// readSetupFile reads the setup file and returns the project name and the
+// config values to create it with
+func readSetupFile(path string) (string, map[string]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", nil, fmt.Errorf("error reading setup file: %w", err)
+	}
+
+	var setup SetupFile
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&setup); err != nil {
+		return "", nil, fmt.Errorf("error parsing setup file: %w", err)
+	}
+
+	preset := map[string]string{}
+	for k, v := range setup.Env {
+		preset[k] = v
+	}
+
+	if setup.FullName != "" {
+		preset["PROJECT_NAME"] = setup.FullName
+	}
+	if setup.EngineVersion != "" {
+		preset["ENGINE_VERSION"] = setup.EngineVersion
+	}
+	setup.SystemDB.addTo(preset, "SYSTEM")
+	setup.ProjectDB.addTo(preset, "PROJECT")
+
+	return strings.TrimSpace(setup.Name), preset, nil
+}
+
+func (db SetupDatabase) addTo(preset map[string]string, prefix string) {
+	values := map[string]string{
+		"ENGINE": db.Engine,
+		"HOST":   db.Host,
+		"PORT":   db.Port,
+		"USER":   db.User,
+		"PASS":   db.Pass,
+		"NAME":   db.Name,
+	}
+	for k, v := range values {
+		if v != "" {
+			preset[prefix+"_DB_"+k] = v
+		}
+	}
+}
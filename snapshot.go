@@ -0,0 +1,301 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mholt/archiver/v3"
+	"github.com/spf13/cobra"
+)
+
+// SnapshotDir holds the snapshots inside the project directory
+const SnapshotDir = "snapshots"
+
+var snapshotExtensions = []string{".tar.gz", ".tar"}
+
+func init() {
+	snapshotCreateCmd.Flags().String("name", "", "Snapshot name (default the current time)")
+	snapshotCreateCmd.Flags().Bool("compress", false, "Compress the snapshot with gzip")
+	snapshotRestoreCmd.ValidArgsFunction = completeSnapshots
+
+	snapshotCmd.AddCommand(snapshotCreateCmd)
+	snapshotCmd.AddCommand(snapshotRestoreCmd)
+	snapshotCmd.AddCommand(snapshotListCmd)
+}
+
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Save and restore the local databases of a project",
+	Long:  `Copy the local database files of a project into a snapshot before risky changes and restore them later.`,
+}
+
+var snapshotCreateCmd = &cobra.Command{
+	Annotations: mutatingAnnotations,
+	Use:         "create",
+	Short:       "Save the project databases in a snapshot",
+	Run: func(cmd *cobra.Command, args []string) {
+		project, _ := cmd.Flags().GetString("project")
+		name, _ := cmd.Flags().GetString("name")
+		compress, _ := cmd.Flags().GetBool("compress")
+
+		if project == "" {
+			logError("Error: --project is required")
+			return
+		}
+
+		if dryRunSkip("save the databases of %s in a snapshot", project) {
+			return
+		}
+		path, err := createSnapshot(project, name, compress)
+		if err != nil {
+			logError("Error creating snapshot:", err)
+			return
+		}
+		fmt.Println(Green+"Snapshot saved to"+Reset, path)
+	},
+}
+
+var snapshotRestoreCmd = &cobra.Command{
+	Annotations: mutatingAnnotations,
+	Use:         "restore <name>",
+	Short:       "Replace the project databases with a snapshot",
+	Args:        cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		project, _ := cmd.Flags().GetString("project")
+		if project == "" {
+			logError("Error: --project is required")
+			return
+		}
+
+		if !confirmSensitiveOperation(fmt.Sprintf("Replace the databases of %s with snapshot %s", project, args[0]), riskDestructive) {
+			return
+		}
+
+		if dryRunSkip("replace the databases of %s with snapshot %s", project, args[0]) {
+			return
+		}
+		if err := restoreSnapshot(project, args[0]); err != nil {
+			logError("Error restoring snapshot:", err)
+			return
+		}
+		fmt.Println(Green+"Snapshot restored:"+Reset, args[0])
+	},
+}
+
+var snapshotListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the snapshots of a project",
+	Run: func(cmd *cobra.Command, args []string) {
+		project, _ := cmd.Flags().GetString("project")
+		if project == "" {
+			logError("Error: --project is required")
+			return
+		}
+
+		snapshots, err := listSnapshots(project)
+		if err != nil {
+			logError("Error reading snapshots:", err)
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "NAME\tSIZE\tCREATED")
+		for _, s := range snapshots {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", s.Name, formatBytes(s.Size), s.Created.Local().Format("2006-01-02 15:04"))
+		}
+		w.Flush()
+	},
+}
+
+type snapshot struct {
+	Name    string
+	Path    string
+	Size    int64
+	Created time.Time
+}
+
+func getSnapshotDir(project string) (string, error) {
+	apitoDir, err := getApitoDir()
+	if err != nil {
+		return "", err
+	}
+	projectDir := filepath.Join(apitoDir, project)
+	if _, err := os.Stat(filepath.Join(projectDir, ConfigFile)); err != nil {
+		return "", fmt.Errorf("project %s does not exist", project)
+	}
+	return filepath.Join(projectDir, SnapshotDir), nil
+}
+
+// createSnapshot stops the engine and archives the database directories of
+// the project, the engine is not restarted
+func createSnapshot(project, name string, compress bool) (string, error) {
+	snapshotDir, err := getSnapshotDir(project)
+	if err != nil {
+		return "", err
+	}
+	projectDir := filepath.Dir(snapshotDir)
+
+	if name == "" {
+		name = time.Now().Format("20060102-150405")
+	}
+	if err := validatePathName(name); err != nil {
+		return "", fmt.Errorf("invalid snapshot name: %w", err)
+	}
+	if _, err := findSnapshot(snapshotDir, name); err == nil {
+		return "", fmt.Errorf("snapshot %s already exists", name)
+	}
+
+	var sources []string
+	for _, dir := range projectDataDirs {
+		if _, err := os.Stat(filepath.Join(projectDir, dir)); err == nil {
+			sources = append(sources, filepath.Join(projectDir, dir))
+		}
+	}
+	if len(sources) == 0 {
+		return "", fmt.Errorf("project %s has no local databases", project)
+	}
+
+	stopRunningEngine(project, projectDir)
+
+	if err := os.MkdirAll(snapshotDir, 0755); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(snapshotDir, name+".tar")
+	if compress {
+		path += ".gz"
+	}
+	logDebug("Archiving", strings.Join(sources, ", "), "to", path)
+	if err := archiver.Archive(sources, path); err != nil {
+		os.Remove(path)
+		return "", err
+	}
+	return path, nil
+}
+
+// restoreSnapshot replaces the database directories of the project with the
+// snapshot, the current databases are put back if the restore fails
+func restoreSnapshot(project, name string) error {
+	snapshotDir, err := getSnapshotDir(project)
+	if err != nil {
+		return err
+	}
+	projectDir := filepath.Dir(snapshotDir)
+
+	if err := validatePathName(name); err != nil {
+		return fmt.Errorf("invalid snapshot name: %w", err)
+	}
+	path, err := findSnapshot(snapshotDir, name)
+	if err != nil {
+		return err
+	}
+
+	stopRunningEngine(project, projectDir)
+
+	moved := map[string]string{}
+	for _, dir := range projectDataDirs {
+		current := filepath.Join(projectDir, dir)
+		if _, err := os.Stat(current); os.IsNotExist(err) {
+			continue
+		}
+		aside := current + ".restore"
+		os.RemoveAll(aside)
+		if err := os.Rename(current, aside); err != nil {
+			rollbackRestore(moved)
+			return err
+		}
+		moved[current] = aside
+	}
+
+	if err := archiver.Unarchive(path, projectDir); err != nil {
+		rollbackRestore(moved)
+		return err
+	}
+
+	for _, aside := range moved {
+		os.RemoveAll(aside)
+	}
+	return nil
+}
+
+func rollbackRestore(moved map[string]string) {
+	for current, aside := range moved {
+		os.RemoveAll(current)
+		if err := os.Rename(aside, current); err != nil {
+			logError("Error putting back", current+":", err)
+		}
+	}
+}
+
+func findSnapshot(snapshotDir, name string) (string, error) {
+	for _, ext := range snapshotExtensions {
+		path := filepath.Join(snapshotDir, name+ext)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("snapshot %s not found", name)
+}
+
+// listSnapshots returns the snapshots of the project, oldest first
+func listSnapshots(project string) ([]snapshot, error) {
+	snapshotDir, err := getSnapshotDir(project)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(snapshotDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var snapshots []snapshot
+	for _, entry := range entries {
+		for _, ext := range snapshotExtensions {
+			name, ok := strings.CutSuffix(entry.Name(), ext)
+			if !ok || entry.IsDir() {
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil {
+				break
+			}
+			snapshots = append(snapshots, snapshot{
+				Name:    name,
+				Path:    filepath.Join(snapshotDir, entry.Name()),
+				Size:    info.Size(),
+				Created: info.ModTime(),
+			})
+			break
+		}
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Created.Before(snapshots[j].Created)
+	})
+	return snapshots, nil
+}
+
+func completeSnapshots(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	project, _ := cmd.Flags().GetString("project")
+	snapshots, err := listSnapshots(project)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var names []string
+	for _, s := range snapshots {
+		if strings.HasPrefix(s.Name, toComplete) {
+			names = append(names, s.Name)
+		}
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
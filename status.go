@@ -0,0 +1,202 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// exit codes of apito status --exit-code, the first failing check decides
+const (
+	statusExitConfigMissing = 2
+	statusExitEngineDown    = 3
+	statusExitDBDown        = 4
+	statusExitConsoleDown   = 5
+)
+
+const statusCheckTimeout = 5 * time.Second
+
+func init() {
+	statusCmd.Flags().Bool("exit-code", false, "Exit with a non-zero code when a check fails: 2 config missing, 3 engine down, 4 database down, 5 console down")
+	statusCmd.Flags().Bool("json", false, "Print the result as JSON")
+}
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Check the health of a project",
+	Long:  `Check the project config, the engine, the databases and the console of a project. With --exit-code the result is reported through the exit code for CI pipelines and provisioning scripts.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		project, _ := cmd.Flags().GetString("project")
+		withExitCode, _ := cmd.Flags().GetBool("exit-code")
+		asJSON, _ := cmd.Flags().GetBool("json")
+
+		if project == "" {
+			logError("Error: --project is required")
+			return
+		}
+
+		status := checkProjectStatus(project)
+
+		if asJSON {
+			out, _ := json.MarshalIndent(status, "", "  ")
+			fmt.Println(string(out))
+		} else {
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+			fmt.Fprintln(w, "CHECK\tSTATUS\tDETAIL")
+			for _, c := range status.Checks {
+				color := Green
+				switch c.Status {
+				case "down", "missing":
+					color = Red
+				case "skipped":
+					color = Gray
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\n", c.Name, color+c.Status+Reset, valueOrDash(c.Detail))
+			}
+			w.Flush()
+		}
+
+		if withExitCode && !status.Healthy {
+			exitCode = status.exitCode
+		}
+	},
+}
+
+type projectStatus struct {
+	Project  string        `json:"project"`
+	Healthy  bool          `json:"healthy"`
+	Checks   []statusCheck `json:"checks"`
+	exitCode int
+}
+
+type statusCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+func (s *projectStatus) add(name, status, detail string, exitCode int) {
+	s.Checks = append(s.Checks, statusCheck{Name: name, Status: status, Detail: detail})
+	if status == "down" || status == "missing" {
+		if s.Healthy {
+			s.exitCode = exitCode
+		}
+		s.Healthy = false
+	}
+}
+
+// checkProjectStatus runs every check of the project, checks that depend on
+// the config are skipped when it is missing
+func checkProjectStatus(project string) *projectStatus {
+	status := &projectStatus{Project: project, Healthy: true}
+
+	apitoDir, err := getApitoDir()
+	if err != nil {
+		status.add("config", "missing", err.Error(), statusExitConfigMissing)
+		return status
+	}
+	projectDir := filepath.Join(apitoDir, project)
+
+	envMap, err := getConfig(projectDir)
+	if err != nil {
+		status.add("config", "missing", filepath.Join(projectDir, ConfigFile)+" not found", statusExitConfigMissing)
+		return status
+	}
+	var missing []string
+	for _, key := range requiredProjectKeys {
+		if envMap[key] == "" {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		status.add("config", "missing", fmt.Sprintf("missing %v", missing), statusExitConfigMissing)
+	} else {
+		status.add("config", "ok", "", 0)
+	}
+
+	status.add(checkEngine(project, envMap))
+
+	for _, db := range []struct{ prefix, name string }{{"SYSTEM", "system db"}, {"PROJECT", "project db"}} {
+		prefix, name := db.prefix, db.name
+		engine := envMap[prefix+"_DB_ENGINE"]
+		switch engine {
+		case "postgres", "mysql", "mariadb":
+			if err := pingDatabase(engine, prefix, envMap); err != nil {
+				status.add(name, "down", err.Error(), statusExitDBDown)
+			} else {
+				status.add(name, "ok", engine, 0)
+			}
+		case "badger":
+			if _, err := os.Stat(filepath.Join(projectDir, "db")); err != nil {
+				status.add(name, "missing", "local database not created yet", statusExitDBDown)
+			} else {
+				status.add(name, "ok", engine, 0)
+			}
+		default:
+			status.add(name, "skipped", valueOrDash(engine), 0)
+		}
+	}
+
+	if consoleURL := envMap["CONSOLE_URL"]; consoleURL != "" {
+		if err := checkURL(consoleURL, nil); err != nil {
+			status.add("console", "down", err.Error(), statusExitConsoleDown)
+		} else {
+			status.add("console", "ok", consoleURL, 0)
+		}
+	} else {
+		status.add("console", "skipped", "CONSOLE_URL not set", 0)
+	}
+
+	return status
+}
+
+// checkEngine reports the engine as down when its process has exited or it
+// does not answer on ENGINE_URL
+func checkEngine(project string, envMap map[string]string) (string, string, string, int) {
+	if pidStr := envMap["ENGINE_PID"]; pidStr != "" {
+		if pid, err := strconv.Atoi(pidStr); err != nil || !isProcessRunning(pid) {
+			return "engine", "down", "process " + pidStr + " is not running", statusExitEngineDown
+		}
+	}
+
+	endpoint, err := getEngineEndpoint(project, "", "")
+	if err != nil {
+		return "engine", "down", err.Error(), statusExitEngineDown
+	}
+	if err := checkURL(endpoint.URL, endpoint); err != nil {
+		return "engine", "down", err.Error(), statusExitEngineDown
+	}
+	return "engine", "ok", endpoint.URL, 0
+}
+
+// checkURL reports whether anything answers on url, any HTTP status below
+// 500 counts as up
+func checkURL(url string, endpoint *engineEndpoint) error {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	var resp *http.Response
+	if endpoint != nil {
+		resp, err = endpoint.do(req, statusCheckTimeout)
+	} else {
+		resp, err = newHTTPClient(statusCheckTimeout).Do(req)
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 500 {
+		return fmt.Errorf("status code %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -6,67 +6,120 @@ import (
 	"path/filepath"
 	"strconv"
 	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 )
 
+// EngineStopFile is written next to the project config by apito stop before
+// it signals the engine, so apito run --supervise knows the engine was
+// stopped on purpose and does not start it again
+const EngineStopFile = "engine.stop"
+
+func init() {
+	stopCmd.Flags().Duration("grace-period", engineStopTimeout, "How long the engine gets to shut down before it is killed")
+}
+
 var stopCmd = &cobra.Command{
-	Use:   "stop",
-	Short: "Stop the engine for the specified project",
-	Long:  `Stop the engine process based on the PID stored in ~/.apito/<project>/.env file`,
+	Annotations: mutatingAnnotations,
+	Use:         "stop",
+	Short:       "Stop the engine for the specified project",
+	Long:        `Stop the engine process based on the PID stored in ~/.apito/<project>/.env file. The engine is asked to shut down with SIGTERM so it can finish requests and flush its databases, and only killed when it is still running after the grace period.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		project, _ := cmd.Flags().GetString("project")
+		gracePeriod, _ := cmd.Flags().GetDuration("grace-period")
 		if project == "" {
-			fmt.Println("Error: --project is required")
+			logError("Error: --project is required")
+			return
+		}
+		if !confirmSensitiveOperation(fmt.Sprintf("Stop the engine of %s", project), riskNormal) {
 			return
 		}
-		stopEngine(project)
+		stopEngine(project, gracePeriod)
 	},
 }
 
-func stopEngine(project string) {
+// stopEngine sends SIGTERM to the engine of the project and waits for it to
+// exit, it is killed when it is still running after gracePeriod
+func stopEngine(project string, gracePeriod time.Duration) {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
-		fmt.Println("Error finding home directory:", err)
+		logError("Error finding home directory:", err)
 		return
 	}
 	projectDir := filepath.Join(homeDir, ".apito", project)
 
 	envMap, err := getConfig(projectDir)
 	if err != nil {
-		fmt.Println("Error reading config file:", err)
+		logError("Error reading config file:", err)
 		return
 	}
 
 	pidStr, ok := envMap["ENGINE_PID"]
 	if !ok {
-		fmt.Println("No running engine PID found in config file")
+		logError("No running engine PID found in config file")
 		return
 	}
 
 	pid, err := strconv.Atoi(pidStr)
 	if err != nil {
-		fmt.Println("Invalid PID in config file:", err)
+		logError("Invalid PID in config file:", err)
 		return
 	}
 
 	process, err := os.FindProcess(pid)
 	if err != nil {
-		fmt.Println("Error finding process:", err)
+		logError("Error finding process:", err)
 		return
 	}
 
+	if dryRunSkip("send SIGTERM to the engine (pid %d) and SIGKILL if it is still running after %s", pid, gracePeriod) {
+		return
+	}
+	if err := os.WriteFile(filepath.Join(projectDir, EngineStopFile), []byte(pidStr+"\n"), 0600); err != nil {
+		logWarn("Error recording the stop, a supervised engine may be restarted:", err)
+	}
 	if err := process.Signal(syscall.SIGTERM); err != nil {
-		fmt.Println("Error stopping engine process:", err)
+		logError("Error stopping engine process:", err)
 		return
 	}
 
+	logInfo(fmt.Sprintf("Waiting up to %s for the engine to shut down...", gracePeriod))
+	if !waitForExit(pid, gracePeriod) {
+		logWarn(fmt.Sprintf("The engine did not shut down within %s, killing it", gracePeriod))
+		if err := process.Signal(syscall.SIGKILL); err != nil {
+			logError("Error killing engine process:", err)
+			return
+		}
+		waitForExit(pid, 5*time.Second)
+	}
+
 	// Remove the PID from the .env file
 	err = updateConfig(projectDir, "ENGINE_PID", "")
 	if err != nil {
-		fmt.Println("Error updating config file:", err)
+		logError("Error updating config file:", err)
 		return
 	}
 
 	fmt.Println("Engine process stopped")
 }
+
+// waitForExit polls until the process is gone and reports whether it exited
+// within timeout
+func waitForExit(pid int, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for isProcessRunning(pid) {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+	return true
+}
+
+// engineStopRequested reports whether apito stop asked the engine of the
+// project to stop since it was started
+func engineStopRequested(projectDir string) bool {
+	_, err := os.Stat(filepath.Join(projectDir, EngineStopFile))
+	return err == nil
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const TelemetryURL = "https://telemetry.apito.io/v1/cli"
+const telemetrySpoolFile = "telemetry.jsonl"
+const telemetryBatchSize = 20
+
+// telemetrySpoolLimit caps the spool while uploads fail, the oldest events
+// are dropped first
+const telemetrySpoolLimit = 500
+
+// after a failed upload the next one waits telemetryRetryMin, doubled with
+// every further failure up to telemetryRetryMax
+const (
+	telemetryRetryMin = time.Hour
+	telemetryRetryMax = 24 * time.Hour
+)
+
+var telemetryCmd = &cobra.Command{
+	Annotations: mutatingAnnotations,
+	Use:         "telemetry",
+	Short:       "Enable or disable anonymous usage reporting",
+	Long: `Enable or disable anonymous usage reporting. Telemetry is off by default.
+
+When enabled, only the command name, its duration, whether it succeeded and
+the OS/architecture are recorded. Arguments, flags, project names and config
+values are never sent. Events are spooled in ~/.apito/telemetry.jsonl and
+uploaded in batches.`,
+	ValidArgs: []string{"enable", "disable", "status"},
+	Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	Run: func(cmd *cobra.Command, args []string) {
+		switch args[0] {
+		case "enable":
+			if err := updateGlobalConfig("TELEMETRY", "enabled"); err != nil {
+				logError("Error updating config:", err)
+				return
+			}
+			fmt.Println(Green + "Telemetry enabled. Thank you for helping improve Apito!" + Reset)
+		case "disable":
+			if err := updateGlobalConfig("TELEMETRY", "disabled"); err != nil {
+				logError("Error updating config:", err)
+				return
+			}
+			if err := clearTelemetrySpool(); err != nil {
+				logError("Error removing spooled events:", err)
+				return
+			}
+			fmt.Println("Telemetry disabled.")
+		case "status":
+			if isTelemetryEnabled() {
+				fmt.Println("Telemetry is enabled")
+			} else {
+				fmt.Println("Telemetry is disabled")
+			}
+			events, _ := readTelemetrySpool()
+			fmt.Printf("%d event(s) waiting to be uploaded\n", len(events))
+		}
+	},
+}
+
+type telemetryEvent struct {
+	Command    string    `json:"command"`
+	DurationMs int64     `json:"duration_ms"`
+	Success    bool      `json:"success"`
+	OS         string    `json:"os"`
+	Arch       string    `json:"arch"`
+	Time       time.Time `json:"time"`
+}
+
+func isTelemetryEnabled() bool {
+	config, err := getGlobalConfig()
+	if err != nil {
+		return false
+	}
+	return config["TELEMETRY"] == "enabled"
+}
+
+func getTelemetrySpoolPath() (string, error) {
+	apitoDir, err := getApitoDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(apitoDir, telemetrySpoolFile), nil
+}
+
+// recordTelemetry spools an event for the executed command when the user has
+// opted in, and uploads the spool once a full batch is collected. Failures
+// are silent, telemetry must never get in the way of the command itself.
+func recordTelemetry(cmd *cobra.Command, duration time.Duration, success bool) {
+	// a dry run only shows what a command would do, it is not usage
+	if cmd == nil || cmd.Hidden || dryRun || !isTelemetryEnabled() {
+		return
+	}
+
+	event, err := json.Marshal(telemetryEvent{
+		Command:    cmd.CommandPath(),
+		DurationMs: duration.Milliseconds(),
+		Success:    success,
+		OS:         runtime.GOOS,
+		Arch:       runtime.GOARCH,
+		Time:       time.Now().UTC(),
+	})
+	if err != nil {
+		return
+	}
+
+	cache := readReleaseCache()
+	canUpload := time.Since(cache.TelemetryFailedAt) >= telemetryRetryDelay(cache.TelemetryFailures)
+
+	// a full batch is taken out of the spool under the lock, so another
+	// process neither uploads it again nor loses the events it appends
+	var batch []json.RawMessage
+	err = updateTelemetrySpool(func(events []json.RawMessage) []json.RawMessage {
+		events = capTelemetryEvents(append(events, event))
+		if canUpload && len(events) >= telemetryBatchSize {
+			batch = events
+			return nil
+		}
+		return events
+	})
+	if err != nil || batch == nil {
+		return
+	}
+
+	err = uploadTelemetry(batch)
+	updateReleaseCache(func(cache *releaseCache) {
+		if err != nil {
+			cache.TelemetryFailedAt = time.Now()
+			cache.TelemetryFailures++
+		} else {
+			cache.TelemetryFailedAt = time.Time{}
+			cache.TelemetryFailures = 0
+		}
+	})
+	if err != nil {
+		// put the batch back in front of the events spooled meanwhile
+		updateTelemetrySpool(func(events []json.RawMessage) []json.RawMessage {
+			return capTelemetryEvents(append(batch, events...))
+		})
+	}
+}
+
+// capTelemetryEvents drops the oldest events beyond telemetrySpoolLimit
+func capTelemetryEvents(events []json.RawMessage) []json.RawMessage {
+	if len(events) > telemetrySpoolLimit {
+		return events[len(events)-telemetrySpoolLimit:]
+	}
+	return events
+}
+
+// telemetryRetryDelay is how long to wait after the given number of failed
+// uploads in a row
+func telemetryRetryDelay(failures int) time.Duration {
+	if failures == 0 {
+		return 0
+	}
+	delay := telemetryRetryMin
+	for i := 1; i < failures && delay < telemetryRetryMax; i++ {
+		delay *= 2
+	}
+	return min(delay, telemetryRetryMax)
+}
+
+func readTelemetrySpool() ([]json.RawMessage, error) {
+	spoolPath, err := getTelemetrySpoolPath()
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(spoolPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return parseTelemetryEvents(f)
+}
+
+func parseTelemetryEvents(r io.Reader) ([]json.RawMessage, error) {
+	var events []json.RawMessage
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if json.Valid(line) {
+			events = append(events, json.RawMessage(append([]byte(nil), line...)))
+		}
+	}
+	return events, scanner.Err()
+}
+
+// updateTelemetrySpool replaces the spooled events with the ones returned by
+// update, while holding an exclusive lock on the spool. The spool is
+// rewritten in place, a rename would leave other processes locking the old
+// file.
+func updateTelemetrySpool(update func(events []json.RawMessage) []json.RawMessage) error {
+	spoolPath, err := getTelemetrySpoolPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(spoolPath), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(spoolPath, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	// closing the file releases the lock
+	defer f.Close()
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		return err
+	}
+
+	events, err := parseTelemetryEvents(f)
+	if err != nil {
+		return err
+	}
+	events = update(events)
+
+	var buf bytes.Buffer
+	for _, event := range events {
+		buf.Write(event)
+		buf.WriteByte('\n')
+	}
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+	_, err = f.WriteAt(buf.Bytes(), 0)
+	return err
+}
+
+func clearTelemetrySpool() error {
+	spoolPath, err := getTelemetrySpoolPath()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(spoolPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func uploadTelemetry(events []json.RawMessage) error {
+	body, err := json.Marshal(map[string]interface{}{"events": events})
+	if err != nil {
+		return err
+	}
+
+	resp, err := newHTTPClient(3*time.Second).Post(TelemetryURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("telemetry upload failed: status code %d", resp.StatusCode)
+	}
+
+	return nil
+}
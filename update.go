@@ -2,23 +2,29 @@ package main
 
 import (
 	"fmt"
-	"io/ioutil"
+	"io"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
 
+	"github.com/mholt/archiver/v3"
 	"github.com/spf13/cobra"
 )
 
 func init() {
-	updateCmd.Flags().StringP("version", "v", "", "Adds a function for that project")
+	updateCmd.Flags().StringP("version", "v", "", "Version to install, e.g. v1.2.3 (default latest)")
+	updateCmd.Flags().Bool("list", false, "List the available cli releases")
 }
 
 var updateCmd = &cobra.Command{
-	Use:       "update",
-	Short:     "Update apito engine and console",
-	Long:      `Update the apito engine and console to the latest version.`,
-	ValidArgs: []string{"engine", "console"},
-	Args:      cobra.MatchAll(cobra.MinimumNArgs(1), cobra.OnlyValidArgs),
+	Annotations: mutatingAnnotations,
+	Use:         "update",
+	Short:       "Update apito engine, console or the cli itself",
+	Long:        `Update the apito engine, console or the cli to the latest or the given version.`,
+	ValidArgs:   []string{"engine", "console", "cli"},
+	Args:        cobra.MatchAll(cobra.MinimumNArgs(1), cobra.OnlyValidArgs),
 	Run: func(cmd *cobra.Command, args []string) {
 		project, _ := cmd.Flags().GetString("project")
 		version, _ := cmd.Flags().GetString("version")
@@ -29,7 +35,13 @@ var updateCmd = &cobra.Command{
 		case "engine":
 			replaceEngine(project, version)
 		case "console":
-			replaceConsole(project, version)
+			replaceConsole(version)
+		case "cli":
+			if list, _ := cmd.Flags().GetBool("list"); list {
+				listCLIReleases()
+				return
+			}
+			updateCLI(version)
 		}
 	},
 }
@@ -37,43 +49,219 @@ var updateCmd = &cobra.Command{
 func replaceEngine(projectName, version string) {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
-		fmt.Println("Error finding home directory:", err)
+		logError("Error finding home directory:", err)
 		return
 	}
 	projectDir := filepath.Join(homeDir, ".apito", projectName)
 
+	envMap, _ := getConfig(projectDir)
+	installed := envMap["ENGINE_VERSION"]
 	if version == "" {
-		fmt.Println("No version specified, pulling latest version")
-		releaseTag, err := getLatestReleaseTag()
+		releaseTag, err := selectVersion(EngineRepo, installed)
 		if err != nil {
-			fmt.Println("error fetching latest release tag: %w", err)
+			logError("Error selecting version:", err)
 			return
 		}
 		version = releaseTag
 	}
 
+	showChangelog(EngineRepo, installed, version)
+	if !confirmSensitiveOperation(fmt.Sprintf("Install engine %s", version), riskNormal) {
+		return
+	}
+
 	// Detect runtime environment and download the appropriate asset
 	if err := downloadAndExtractEngine(projectName, version, projectDir); err != nil {
-		fmt.Println("Error downloading and extracting binary:", err)
+		logError("Error downloading and extracting binary:", err)
 		return
 	}
 }
-func replaceConsole(projectName, version string) {
-	homeDir, err := os.UserHomeDir()
+
+// replaceConsole installs a console release into ~/.apito/console, which is
+// shared by all projects
+func replaceConsole(version string) {
+	installed := getInstalledVersion("", consoleComponent)
+	if version == "" {
+		releaseTag, err := selectVersion(ConsoleRepo, installed)
+		if err != nil {
+			logError("Error selecting version:", err)
+			return
+		}
+		version = releaseTag
+	}
+
+	apitoDir, err := getApitoDir()
+	if err != nil {
+		logError("Error finding home directory:", err)
+		return
+	}
+	if _, err := os.Stat(filepath.Join(apitoDir, ConsoleDir)); err == nil && version == installed {
+		fmt.Println(Green+"Console is already at"+Reset, version)
+		return
+	}
+
+	showChangelog(ConsoleRepo, installed, version)
+	if !confirmSensitiveOperation(fmt.Sprintf("Install console %s", version), riskNormal) {
+		return
+	}
+
+	if err := downloadAndExtractConsole(version); err != nil {
+		logError("Error downloading and extracting console:", err)
+		return
+	}
+}
+
+// listCLIReleases prints the published cli releases, newest first
+func listCLIReleases() {
+	tags, err := getRepoReleaseTags(CLIRepo)
+	if err != nil {
+		logError("Error fetching cli releases:", err)
+		return
+	}
+
+	for _, tag := range sortVersions(tags) {
+		if tag == version {
+			fmt.Println(Green + tag + " (installed)" + Reset)
+		} else {
+			fmt.Println(tag)
+		}
+	}
+}
+
+// updateCLI replaces the running binary with the given release of the cli,
+// going back to an older release needs an explicit confirmation
+func updateCLI(target string) {
+	if target == "" {
+		selected, err := selectVersion(CLIRepo, version)
+		if err != nil {
+			logError("Error selecting version:", err)
+			return
+		}
+		target = selected
+	}
+
+	if target == version {
+		fmt.Println("apito", version, "is already installed")
+		return
+	}
+	if compareVersions(target, version) < 0 {
+		logWarn(fmt.Sprintf("%s is older than the installed version %s", target, version))
+		if !confirmSensitiveOperation("Downgrade the cli", riskDestructive) {
+			return
+		}
+	} else {
+		showChangelog(CLIRepo, version, target)
+		if !confirmSensitiveOperation(fmt.Sprintf("Install cli %s", target), riskNormal) {
+			return
+		}
+	}
+
+	executable, err := os.Executable()
 	if err != nil {
-		fmt.Println("Error finding home directory:", err)
+		logError("Error finding the cli binary:", err)
+		return
+	}
+	executable, err = filepath.EvalSymlinks(executable)
+	if err != nil {
+		logError("Error finding the cli binary:", err)
+		return
+	}
+
+	asset := fmt.Sprintf("apito-%s-%s.zip", runtime.GOOS, runtime.GOARCH)
+	assetURL := releaseDownloadURL(CLIRepo, target, asset)
+	if dryRunSkip("download %s and replace %s with it", assetURL, executable) {
 		return
 	}
-	apitoDir := filepath.Join(homeDir, ".apito")
-	files, err := ioutil.ReadDir(apitoDir)
+
+	tmpDir, err := os.MkdirTemp("", "apito-cli")
 	if err != nil {
-		fmt.Println("Error reading apito directory:", err)
+		logError("Error creating temporary directory:", err)
+		return
+	}
+	defer os.RemoveAll(tmpDir)
+
+	logInfo("Downloading cli from:", assetURL)
+	filename := filepath.Join(tmpDir, asset)
+	if err := downloadFile(assetURL, filename); err != nil {
+		logError("Error downloading cli:", err)
+		return
+	}
+	if err := archiver.Unarchive(filename, tmpDir); err != nil {
+		logError("Error extracting cli:", err)
+		return
+	}
+
+	// move the new binary next to the old one first so the final rename
+	// does not cross file systems and replaces the binary atomically
+	staged := executable + ".new"
+	if err := copyFile(filepath.Join(tmpDir, "apito"), staged, 0755); err != nil {
+		logError("Error installing cli:", err)
+		return
+	}
+	if err := os.Rename(staged, executable); err != nil {
+		os.Remove(staged)
+		logError("Error installing cli:", err)
 		return
 	}
 
-	for _, f := range files {
-		if f.IsDir() {
-			fmt.Println(f.Name())
+	fmt.Println(Green+"apito updated to"+Reset, target)
+}
+
+func copyFile(src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// compareVersions compares two vX.Y.Z versions numerically, versions that
+// cannot be parsed such as dev builds sort before every release
+func compareVersions(a, b string) int {
+	pa, okA := parseVersion(a)
+	pb, okB := parseVersion(b)
+	switch {
+	case !okA && !okB:
+		return strings.Compare(a, b)
+	case !okA:
+		return -1
+	case !okB:
+		return 1
+	}
+	for i := range pa {
+		if pa[i] != pb[i] {
+			if pa[i] < pb[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func parseVersion(v string) ([3]int, bool) {
+	var parts [3]int
+	v, _, _ = strings.Cut(strings.TrimPrefix(v, "v"), "-")
+	fields := strings.Split(v, ".")
+	if len(fields) == 0 || len(fields) > 3 {
+		return parts, false
+	}
+	for i, f := range fields {
+		n, err := strconv.Atoi(f)
+		if err != nil {
+			return parts, false
 		}
+		parts[i] = n
 	}
+	return parts, true
 }
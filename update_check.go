@@ -0,0 +1,217 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// CacheFile holds the latest release of every component so the update check
+// does not call GitHub on every run
+const CacheFile = "cache.yml"
+
+const DefaultUpdateCheckTTL = 6 * time.Hour
+
+// cliUpdateNoticeInterval is how often the user is told about a new cli
+// release
+const cliUpdateNoticeInterval = 24 * time.Hour
+
+// cliUpdateCheckWait is how long a finished command waits for the cli
+// update check before exiting without it
+const cliUpdateCheckWait = time.Second
+
+type releaseCache struct {
+	Releases      map[string]cachedRelease `yaml:"releases"`
+	CLINotifiedAt time.Time                `yaml:"cli_notified_at,omitempty"`
+
+	// TelemetryFailedAt is the last failed telemetry upload, the next one is
+	// delayed by the number of failures in a row
+	TelemetryFailedAt time.Time `yaml:"telemetry_failed_at,omitempty"`
+	TelemetryFailures int       `yaml:"telemetry_failures,omitempty"`
+}
+
+type cachedRelease struct {
+	Tag       string    `yaml:"tag"`
+	CheckedAt time.Time `yaml:"checked_at"`
+}
+
+// component is a part of apito that is released separately, VersionKey is
+// the config key holding the installed version. Shared components are
+// installed once for all projects and their version is kept in
+// ~/.apito/.env instead of the project config.
+type component struct {
+	Name       string
+	Repo       string
+	VersionKey string
+	Shared     bool
+}
+
+var (
+	engineComponent  = component{Name: "engine", Repo: EngineRepo, VersionKey: "ENGINE_VERSION"}
+	consoleComponent = component{Name: "console", Repo: ConsoleRepo, VersionKey: "CONSOLE_VERSION", Shared: true}
+	components       = []component{engineComponent, consoleComponent}
+
+	// cliComponent is this binary, it is not part of a project
+	cliComponent = component{Name: "cli", Repo: CLIRepo}
+)
+
+// checkForComponentUpdates tells the user about newer engine and console
+// releases, set UPDATE_CHECK=false in ~/.apito/.env to disable it
+func checkForComponentUpdates(project string) {
+	config, err := getGlobalConfig()
+	if err == nil && config["UPDATE_CHECK"] == "false" {
+		return
+	}
+
+	latest := getLatestReleases(components)
+	for _, c := range components {
+		installed := getInstalledVersion(project, c)
+		if installed == "" || latest[c.Name] == "" || compareVersions(latest[c.Name], installed) <= 0 {
+			continue
+		}
+		update := fmt.Sprintf("apito update %s -p %s", c.Name, project)
+		if c.Shared {
+			update = "apito update " + c.Name
+		}
+		logInfo(Yellow + fmt.Sprintf("A new %s version is available: %s -> %s, run `%s`", c.Name, installed, latest[c.Name], update) + Reset)
+	}
+}
+
+// getLatestReleases looks up the latest release of the components in
+// parallel, results younger than UPDATE_CHECK_TTL are taken from the cache
+func getLatestReleases(components []component) map[string]string {
+	ttl := getConfigDuration("UPDATE_CHECK_TTL", DefaultUpdateCheckTTL)
+	cache := readReleaseCache()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	latest := map[string]string{}
+	updated := map[string]cachedRelease{}
+
+	for _, c := range components {
+		if cached, ok := cache.Releases[c.Name]; ok && time.Since(cached.CheckedAt) < ttl {
+			latest[c.Name] = cached.Tag
+			continue
+		}
+
+		wg.Add(1)
+		go func(c component) {
+			defer wg.Done()
+			tag, err := getLatestRepoReleaseTag(c.Repo)
+			if err != nil {
+				logDebug("Update check for", c.Name, "failed:", err)
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			latest[c.Name] = tag
+			updated[c.Name] = cachedRelease{Tag: tag, CheckedAt: time.Now()}
+		}(c)
+	}
+	wg.Wait()
+
+	if len(updated) > 0 {
+		err := updateReleaseCache(func(cache *releaseCache) {
+			for name, release := range updated {
+				cache.Releases[name] = release
+			}
+		})
+		if err != nil {
+			logDebug("Error writing release cache:", err)
+		}
+	}
+	return latest
+}
+
+// releaseCacheMu serializes the cache updates of the checks running in the
+// background of the same command
+var releaseCacheMu sync.Mutex
+
+// updateReleaseCache applies change to the current content of the cache
+func updateReleaseCache(change func(cache *releaseCache)) error {
+	releaseCacheMu.Lock()
+	defer releaseCacheMu.Unlock()
+	cache := readReleaseCache()
+	change(cache)
+	return writeReleaseCache(cache)
+}
+
+// startCLIUpdateCheck looks up the latest cli release in the background
+// while a command runs, the returned function prints a notice about it at
+// most once a day. Set UPDATE_CHECK=false in ~/.apito/.env to disable it.
+func startCLIUpdateCheck(cmd *cobra.Command) func() {
+	if version == "dev" || quietOutput || !isTerminal(os.Stderr) {
+		return func() {}
+	}
+	switch cmd.Name() {
+	case "update", "version", "completion", cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd:
+		return func() {}
+	}
+	if config, err := getGlobalConfig(); err == nil && config["UPDATE_CHECK"] == "false" {
+		return func() {}
+	}
+	if time.Since(readReleaseCache().CLINotifiedAt) < cliUpdateNoticeInterval {
+		return func() {}
+	}
+
+	done := make(chan string, 1)
+	go func() {
+		done <- getLatestReleases([]component{cliComponent})[cliComponent.Name]
+	}()
+
+	return func() {
+		var latest string
+		select {
+		case latest = <-done:
+		case <-time.After(cliUpdateCheckWait):
+			logDebug("Skipping the cli update check, it did not finish in time")
+			return
+		}
+		if latest == "" || compareVersions(latest, version) <= 0 {
+			return
+		}
+
+		writeLog("INFO", "A new cli version is available:", version, "->", latest)
+		fmt.Fprintln(os.Stderr, Yellow+fmt.Sprintf("A new cli version is available: %s -> %s, run `apito update cli`", version, latest)+Reset)
+		err := updateReleaseCache(func(cache *releaseCache) {
+			cache.CLINotifiedAt = time.Now()
+		})
+		if err != nil {
+			logDebug("Error writing release cache:", err)
+		}
+	}
+}
+
+func readReleaseCache() *releaseCache {
+	cache := &releaseCache{}
+	if apitoDir, err := getApitoDir(); err == nil {
+		if content, err := os.ReadFile(filepath.Join(apitoDir, CacheFile)); err == nil {
+			if err := yaml.Unmarshal(content, cache); err != nil {
+				logDebug("Ignoring invalid release cache:", err)
+			}
+		}
+	}
+	if cache.Releases == nil {
+		cache.Releases = map[string]cachedRelease{}
+	}
+	return cache
+}
+
+func writeReleaseCache(cache *releaseCache) error {
+	apitoDir, err := getApitoDir()
+	if err != nil {
+		return err
+	}
+	content, err := yaml.Marshal(cache)
+	if err != nil {
+		return err
+	}
+	// the background check may be killed when the command is done, a
+	// truncated cache would break the next lookup
+	return writeFileAtomic(filepath.Join(apitoDir, CacheFile), content, 0644)
+}
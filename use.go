@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/joho/godotenv"
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	useCmd.Flags().Bool("clear", false, "Forget the default project")
+	useCmd.ValidArgsFunction = completeProjects
+}
+
+var useCmd = &cobra.Command{
+	Use:         "use [project]",
+	Short:       "Set the default project",
+	Long:        `Set the default project, stored as DEFAULT_PROJECT in ~/.apito/.env. Without a project name the project is picked from a searchable list that shows the engine URL of every project and whether it answers.`,
+	Annotations: mutatingAnnotations,
+	Args:        cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		clearDefault, _ := cmd.Flags().GetBool("clear")
+
+		apitoDir, err := getApitoDir()
+		if err != nil {
+			logError("Error finding home directory:", err)
+			return
+		}
+
+		if clearDefault {
+			config, err := getGlobalConfig()
+			if err == nil {
+				delete(config, "DEFAULT_PROJECT")
+				err = saveConfig(apitoDir, config)
+			}
+			if err != nil {
+				logError("Error saving config:", err)
+				return
+			}
+			fmt.Println("Default project cleared")
+			return
+		}
+
+		var project string
+		switch {
+		case len(args) == 1:
+			project = args[0]
+			if err := validateProjectExists(project); err != nil {
+				logError("Error:", err)
+				return
+			}
+		case isInteractive():
+			project, err = selectProject(apitoDir)
+			if err != nil {
+				logError("Error:", err)
+				return
+			}
+			if project == "" {
+				return
+			}
+		default:
+			config, _ := getGlobalConfig()
+			if config["DEFAULT_PROJECT"] == "" {
+				fmt.Println("No default project set")
+			} else {
+				fmt.Println(config["DEFAULT_PROJECT"])
+			}
+			return
+		}
+
+		if err := updateGlobalConfig("DEFAULT_PROJECT", project); err != nil {
+			logError("Error saving config:", err)
+			return
+		}
+		fmt.Println(Green+"Now using project"+Reset, project)
+	},
+}
+
+// projectChoice is an entry of the project selector
+type projectChoice struct {
+	Name      string
+	URL       string
+	Indicator string
+}
+
+// selectProject shows every project with its engine URL and reachability
+// and returns the chosen one, or an empty string when aborted
+func selectProject(apitoDir string) (string, error) {
+	projects := listProjectNames(apitoDir)
+	if len(projects) == 0 {
+		return "", fmt.Errorf("no projects found, create one with `apito create project`")
+	}
+
+	choices := make([]projectChoice, len(projects))
+	var wg sync.WaitGroup
+	for i, project := range projects {
+		wg.Add(1)
+		go func(i int, project string) {
+			defer wg.Done()
+			choices[i] = projectChoice{Name: project, Indicator: Gray + "○" + Reset}
+			endpoint, err := getEngineEndpoint(project, "", "")
+			if err != nil {
+				return
+			}
+			choices[i].URL = endpoint.URL
+			if checkURL(endpoint.URL, endpoint) == nil {
+				choices[i].Indicator = Green + "●" + Reset
+			} else {
+				choices[i].Indicator = Red + "●" + Reset
+			}
+		}(i, project)
+	}
+	wg.Wait()
+
+	current := ""
+	if config, err := getGlobalConfig(); err == nil {
+		current = config["DEFAULT_PROJECT"]
+	}
+	cursor := 0
+	for i, c := range choices {
+		if c.Name == current {
+			cursor = i
+		}
+	}
+
+	prompt := promptui.Select{
+		Label: "Select the default project",
+		Items: choices,
+		Templates: &promptui.SelectTemplates{
+			Active:   `▸ {{ .Indicator }} {{ .Name | cyan }}  {{ .URL | faint }}`,
+			Inactive: `  {{ .Indicator }} {{ .Name }}  {{ .URL | faint }}`,
+			Selected: `{{ .Name }}`,
+		},
+		Size:      10,
+		CursorPos: cursor,
+		Searcher: func(input string, index int) bool {
+			return fuzzyMatch(input, choices[index].Name)
+		},
+		StartInSearchMode: true,
+	}
+	i, _, err := prompt.Run()
+	if err != nil {
+		return "", nil
+	}
+	return choices[i].Name, nil
+}
+
+// fuzzyMatch reports whether the characters of pattern appear in s in the
+// same order, ignoring case
+func fuzzyMatch(pattern, s string) bool {
+	s = strings.ToLower(s)
+	for _, r := range strings.ToLower(strings.TrimSpace(pattern)) {
+		i := strings.IndexRune(s, r)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(string(r)):]
+	}
+	return true
+}
+
+// validateProjectExists checks that ~/.apito/<project> has a config
+func validateProjectExists(project string) error {
+	apitoDir, err := getApitoDir()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(filepath.Join(apitoDir, project, ConfigFile)); err != nil {
+		return fmt.Errorf("project %s does not exist", project)
+	}
+	return nil
+}
+
+// ProjectRCFile in a working directory or one of its parents names the
+// project to use there, as PROJECT=<name>
+const ProjectRCFile = ".apitorc"
+
+// explicitProjectAnnotation marks commands where no --project has its own
+// meaning, e.g. all projects or the cli config, so no default is filled in
+const explicitProjectAnnotation = "explicit-project"
+
+// resolveProject returns the project to use when --project is not given and
+// where it comes from: APITO_PROJECT, a .apitorc or DEFAULT_PROJECT
+func resolveProject() (string, string) {
+	if project := os.Getenv("APITO_PROJECT"); project != "" {
+		return project, "APITO_PROJECT"
+	}
+	if project, path := findProjectRC(); project != "" {
+		return project, path
+	}
+	if config, err := getGlobalConfig(); err == nil && config["DEFAULT_PROJECT"] != "" {
+		return config["DEFAULT_PROJECT"], "DEFAULT_PROJECT"
+	}
+	return "", ""
+}
+
+// findProjectRC looks for a .apitorc from the working directory up
+func findProjectRC() (string, string) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", ""
+	}
+	for {
+		path := filepath.Join(dir, ProjectRCFile)
+		if rc, err := godotenv.Read(path); err == nil && rc["PROJECT"] != "" {
+			return rc["PROJECT"], path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", ""
+		}
+		dir = parent
+	}
+}
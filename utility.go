@@ -2,12 +2,17 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"net/http"
+	"maps"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
 
 	"github.com/joho/godotenv"
+	"github.com/manifoldco/promptui"
 )
 
 const ConfigFile = ".env"
@@ -31,17 +36,133 @@ func ArrayContains(arr []string, str string) bool {
 	return false
 }
 
+// parseKeyValues parses a list of KEY=VALUE pairs into a map
+func parseKeyValues(values []string) (map[string]string, error) {
+	result := map[string]string{}
+	for _, v := range values {
+		key, value, ok := strings.Cut(v, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("invalid value %q, expected KEY=VALUE", v)
+		}
+		result[strings.TrimSpace(key)] = value
+	}
+	return result, nil
+}
+
+// confirm asks a yes/no question and reports whether the user agreed
+func confirm(label string) bool {
+	prompt := promptui.Prompt{
+		Label:     label,
+		IsConfirm: true,
+	}
+	_, err := prompt.Run()
+	return err == nil
+}
+
+// forceConfirm is set by --force and skips every confirmation
+var forceConfirm bool
+
+// operationRisk decides which operations ask for confirmation at the
+// CONFIRM_LEVEL in ~/.apito/.env
+type operationRisk int
+
+const (
+	// riskNormal operations change a project but can be redone, e.g. deploy
+	riskNormal operationRisk = iota
+	// riskDestructive operations delete data or go back to an older version
+	riskDestructive
+)
+
+const DefaultConfirmLevel = "destructive"
+
+// confirmSensitiveOperation asks for confirmation unless --force is set or
+// CONFIRM_LEVEL does not require it for the risk of the operation. The
+// levels are all, destructive (the default) and none.
+func confirmSensitiveOperation(label string, risk operationRisk) bool {
+	// nothing is changed in a dry run, so there is nothing to confirm
+	if forceConfirm || dryRun {
+		return true
+	}
+
+	level := DefaultConfirmLevel
+	if config, err := getGlobalConfig(); err == nil && config["CONFIRM_LEVEL"] != "" {
+		level = config["CONFIRM_LEVEL"]
+	}
+
+	switch level {
+	case "none":
+		return true
+	case "all":
+	case "destructive":
+		if risk < riskDestructive {
+			return true
+		}
+	default:
+		logWarn(fmt.Sprintf("Invalid CONFIRM_LEVEL %q in config, using %s", level, DefaultConfirmLevel))
+		if risk < riskDestructive {
+			return true
+		}
+	}
+
+	if nonInteractive {
+		logError("Error:", label, "needs confirmation, use --force")
+		return false
+	}
+	return confirm(label)
+}
+
+// isTerminal reports whether f is a terminal and not a pipe or file
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// isInteractive reports whether prompts can be shown, i.e. stdin is a
+// terminal and no setup file is used
+func isInteractive() bool {
+	return !nonInteractive && isTerminal(os.Stdin)
+}
+
+// isProcessRunning reports whether a process with the pid is alive
+func isProcessRunning(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	return process.Signal(syscall.Signal(0)) == nil
+}
+
+const EngineRepo = "apito-io/engine"
+const ConsoleRepo = "apito-io/console"
+const CLIRepo = "apito-io/cli"
+
 func getLatestReleaseTag() (string, error) {
-	resp, err := http.Get("https://api.github.com/repos/apito-io/engine/releases/latest")
+	return getLatestRepoReleaseTag(EngineRepo)
+}
+
+// getLatestRepoReleaseTag returns the tag of the latest GitHub release of
+// repo, the newest image tag on ghcr.io when GitHub rate limits the request
+func getLatestRepoReleaseTag(repo string) (string, error) {
+	resp, err := githubGet(fmt.Sprintf("https://api.github.com/repos/%s/releases/latest", repo))
+	if errors.Is(err, errGitHubRateLimited) {
+		logDebug("GitHub rate limit reached, listing tags on ghcr.io")
+		tags, err := getGHCRTags(repo)
+		if err != nil {
+			return "", err
+		}
+		if len(tags) == 0 {
+			return "", fmt.Errorf("no releases found for %s", repo)
+		}
+		return tags[0], nil
+	}
 	if err != nil {
 		return "", fmt.Errorf("error fetching latest release: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("failed to fetch latest release: status code %d", resp.StatusCode)
-	}
-
 	var result struct {
 		TagName string `json:"tag_name"`
 	}
@@ -52,6 +173,116 @@ func getLatestReleaseTag() (string, error) {
 	return result.TagName, nil
 }
 
+type githubRelease struct {
+	TagName    string `json:"tag_name"`
+	Body       string `json:"body"`
+	Draft      bool   `json:"draft"`
+	Prerelease bool   `json:"prerelease"`
+}
+
+// getRepoReleases returns the published releases of repo with their release
+// notes, newest first
+func getRepoReleases(repo string) ([]githubRelease, error) {
+	resp, err := githubGet(fmt.Sprintf("https://api.github.com/repos/%s/releases?per_page=100", repo))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var releases []githubRelease
+	if err := json.NewDecoder(resp.Body).Decode(&releases); err != nil {
+		return nil, fmt.Errorf("error decoding response: %w", err)
+	}
+
+	var published []githubRelease
+	for _, r := range releases {
+		if !r.Draft && !r.Prerelease {
+			published = append(published, r)
+		}
+	}
+	return published, nil
+}
+
+// getRepoReleaseTags returns the tags of the published releases of repo,
+// newest first, falling back to the image tags on ghcr.io when GitHub rate
+// limits the request
+func getRepoReleaseTags(repo string) ([]string, error) {
+	releases, err := getRepoReleases(repo)
+	if errors.Is(err, errGitHubRateLimited) {
+		logDebug("GitHub rate limit reached, listing tags on ghcr.io")
+		return getGHCRTags(repo)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching releases: %w", err)
+	}
+
+	var tags []string
+	for _, r := range releases {
+		tags = append(tags, r.TagName)
+	}
+	return tags, nil
+}
+
+// getApitoDir returns ~/.apito which holds the cli config and every project
+func getApitoDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("error finding home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".apito"), nil
+}
+
+// getGlobalConfig reads the cli wide config in ~/.apito/.env, a missing file
+// is treated as an empty config
+func getGlobalConfig() (map[string]string, error) {
+	apitoDir, err := getApitoDir()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := os.Stat(filepath.Join(apitoDir, ConfigFile)); os.IsNotExist(err) {
+		return map[string]string{}, nil
+	}
+
+	config, err := getConfig(apitoDir)
+	if err != nil {
+		return nil, err
+	}
+	unknownKeysWarning.Do(func() {
+		if keys := unknownGlobalConfigKeys(config); len(keys) > 0 {
+			logWarn(fmt.Sprintf("Unknown keys %v in %s, run `apito config doctor`", keys, filepath.Join(apitoDir, ConfigFile)))
+		}
+	})
+	return config, nil
+}
+
+// unknownKeysWarning makes sure the warning is only printed once per run
+var unknownKeysWarning sync.Once
+
+func updateGlobalConfig(key, value string) error {
+	apitoDir, err := getApitoDir()
+	if err != nil {
+		return err
+	}
+
+	envMap, err := getGlobalConfig()
+	if err != nil {
+		return err
+	}
+
+	envMap[key] = value
+
+	if err := os.MkdirAll(apitoDir, 0755); err != nil {
+		return fmt.Errorf("error creating apito directory: %w", err)
+	}
+
+	if err := saveConfig(apitoDir, envMap); err != nil {
+		return fmt.Errorf("error saving config file: %w", err)
+	}
+
+	return nil
+}
+
 func getConfig(projectDir string) (map[string]string, error) {
 	configFile := filepath.Join(projectDir, ConfigFile)
 	envMap, err := godotenv.Read(configFile)
@@ -79,26 +310,84 @@ func updateConfig(projectDir, key, value string) error {
 	return nil
 }
 
+// validatePathName checks that a name given by the user or read from an
+// archive is a single path element, so it cannot point outside ~/.apito
+func validatePathName(name string) error {
+	switch {
+	case name == "":
+		return fmt.Errorf("the name is empty")
+	case strings.ContainsAny(name, `/\`):
+		return fmt.Errorf("%q must not contain a path separator", name)
+	case strings.Contains(name, ".."):
+		return fmt.Errorf("%q must not contain ..", name)
+	}
+	return nil
+}
+
+// equalIgnoringPID reports whether two configs only differ in ENGINE_PID
+func equalIgnoringPID(a, b map[string]string) bool {
+	a, b = maps.Clone(a), maps.Clone(b)
+	delete(a, "ENGINE_PID")
+	delete(b, "ENGINE_PID")
+	return maps.Equal(a, b)
+}
+
+// saveConfig writes the config to a temp file and renames it over the old
+// one, so a crash never leaves a half written config. The old config is kept
+// in the backups directory first.
 func saveConfig(projectDir string, config map[string]string) error {
 	configFile := filepath.Join(projectDir, ConfigFile)
-
-	if _, err := os.Stat(configFile); os.IsNotExist(err) {
-		_, err := os.Create(configFile)
-		if err != nil {
-			return fmt.Errorf("error creating config file: %w", err)
-		}
+	if dryRunSkip("write %s", configFile) {
+		return nil
 	}
 
-	f, err := os.Open(configFile)
+	content, err := godotenv.Marshal(config)
 	if err != nil {
-		return fmt.Errorf("error creating config file: %w", err)
+		return fmt.Errorf("error encoding config: %w", err)
 	}
-	defer f.Close()
 
-	// write the config to the file
-	if err := godotenv.Write(config, configFile); err != nil {
-		return fmt.Errorf("error writing config file: %w", err)
+	// configs hold the login token and database passwords, so they are only
+	// readable by the owner, also when an older cli created them 0644
+	mode := os.FileMode(0600)
+	if info, err := os.Stat(configFile); err == nil {
+		mode = info.Mode().Perm() &^ 0077
+		// start and stop rewrite ENGINE_PID every time, backing that up
+		// would push the real edits out of the kept backups
+		if old, err := godotenv.Read(configFile); err != nil || !equalIgnoringPID(old, config) {
+			if err := backupConfig(projectDir); err != nil {
+				logWarn("Config not backed up:", err)
+			}
+		}
 	}
 
+	if err := writeFileAtomic(configFile, []byte(content+"\n"), mode); err != nil {
+		return fmt.Errorf("error writing config file: %w", err)
+	}
 	return nil
 }
+
+// writeFileAtomic writes data to a temp file next to path and renames it over
+// path, so a crash or a killed process never leaves a half written file
+func writeFileAtomic(path string, data []byte, mode os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"text/tabwriter"
+	"time"
+
+	"github.com/docker/docker/client"
+	"github.com/spf13/cobra"
+)
+
+// commit and date are set at build time next to version with
+// -ldflags "-X main.commit=<sha> -X main.date=<RFC3339 time>"
+var (
+	commit = "none"
+	date   = "unknown"
+)
+
+const dockerVersionTimeout = 3 * time.Second
+
+func init() {
+	versionCmd.Flags().Bool("components", false, "Also print the installed engine and console versions and the Docker version")
+}
+
+var versionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Print the cli version and the installed components",
+	Long:  `Print the version of the cli with the commit and date it was built from. With --components the engine of every project, or only of --project, the console and Docker are listed too, e.g. for bug reports.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		project, _ := cmd.Flags().GetString("project")
+		withComponents, _ := cmd.Flags().GetBool("components")
+
+		fmt.Printf("apito %s (commit %s, built %s, %s, %s/%s)\n", version, commit, date, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+		if !withComponents {
+			return
+		}
+
+		apitoDir, err := getApitoDir()
+		if err != nil {
+			logError("Error finding home directory:", err)
+			return
+		}
+
+		projects := []string{project}
+		if project == "" {
+			projects = listProjectNames(apitoDir)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "COMPONENT\tVERSION")
+		for _, p := range projects {
+			fmt.Fprintf(w, "engine (%s)\t%s\n", p, engineVersionStatus(apitoDir, p))
+		}
+		consoleVersion := getInstalledVersion("", consoleComponent)
+		if _, err := os.Stat(filepath.Join(apitoDir, ConsoleDir)); err != nil {
+			consoleVersion = "not installed"
+		}
+		fmt.Fprintf(w, "console\t%s\n", valueOrDash(consoleVersion))
+		fmt.Fprintf(w, "docker\t%s\n", getDockerVersion())
+		w.Flush()
+	},
+}
+
+// engineVersionStatus returns the recorded engine version of the project,
+// or why there is none
+func engineVersionStatus(apitoDir, project string) string {
+	if _, err := os.Stat(filepath.Join(apitoDir, project, project)); err != nil {
+		return "not installed"
+	}
+	return valueOrDash(getInstalledVersion(project, engineComponent))
+}
+
+// getDockerVersion returns the version of the Docker daemon, or why it is
+// not known
+func getDockerVersion() string {
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		return "not available"
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), dockerVersionTimeout)
+	defer cancel()
+	v, err := cli.ServerVersion(ctx)
+	if err != nil {
+		return "not running"
+	}
+	return fmt.Sprintf("%s (API %s)", v.Version, v.APIVersion)
+}
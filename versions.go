@@ -0,0 +1,235 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+)
+
+const ghcrTokenURL = "https://ghcr.io/token?scope=repository:%s:pull"
+const ghcrTagsURL = "https://ghcr.io/v2/%s/tags/list?n=1000"
+
+var errGitHubRateLimited = errors.New("GitHub API rate limit reached, set GITHUB_TOKEN to raise it")
+
+func init() {
+	versionsCmd.AddCommand(versionsListCmd)
+}
+
+var versionsCmd = &cobra.Command{
+	Use:   "versions",
+	Short: "Show the available versions of apito components",
+}
+
+var versionsListCmd = &cobra.Command{
+	Use:       "list [engine|console|cli]",
+	Short:     "List the released versions of the engine, console or cli",
+	Long:      `List the released versions, newest first, for use with apito update --version. When the GitHub API rate limit is reached the image tags on ghcr.io are listed instead.`,
+	ValidArgs: []string{"engine", "console", "cli"},
+	Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	Run: func(cmd *cobra.Command, args []string) {
+		project, _ := cmd.Flags().GetString("project")
+
+		repo := CLIRepo
+		installed := version
+		for _, c := range components {
+			if c.Name == args[0] {
+				repo = c.Repo
+				installed = getInstalledVersion(project, c)
+			}
+		}
+
+		tags, err := getRepoReleaseTags(repo)
+		if err != nil {
+			logError("Error fetching versions:", err)
+			return
+		}
+
+		for _, tag := range sortVersions(tags) {
+			if tag == installed {
+				fmt.Println(Green + tag + " (installed)" + Reset)
+			} else {
+				fmt.Println(tag)
+			}
+		}
+	},
+}
+
+// getInstalledVersion returns the version of the component recorded in the
+// project config, or in ~/.apito/.env for shared components. It is empty
+// when nothing is installed or no project is given.
+func getInstalledVersion(project string, c component) string {
+	if c.Shared {
+		config, err := getGlobalConfig()
+		if err != nil {
+			return ""
+		}
+		return config[c.VersionKey]
+	}
+	if project == "" {
+		return ""
+	}
+	apitoDir, err := getApitoDir()
+	if err != nil {
+		return ""
+	}
+	envMap, err := getConfig(filepath.Join(apitoDir, project))
+	if err != nil {
+		return ""
+	}
+	return envMap[c.VersionKey]
+}
+
+// githubGet sends a GET request to the GitHub API, authenticated with
+// GITHUB_TOKEN when it is set. A rate limited response is returned as
+// errGitHubRateLimited, any other non 200 status as an error.
+func githubGet(url string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := newHTTPClient(getRequestTimeout()).Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		return resp, nil
+	case resp.StatusCode == http.StatusTooManyRequests,
+		resp.StatusCode == http.StatusForbidden && resp.Header.Get("X-RateLimit-Remaining") == "0":
+		resp.Body.Close()
+		return nil, errGitHubRateLimited
+	}
+	resp.Body.Close()
+	return nil, fmt.Errorf("status code %d", resp.StatusCode)
+}
+
+// getGHCRTags lists the version tags of the image published for repo on
+// ghcr.io with an anonymous pull token, newest first
+func getGHCRTags(repo string) ([]string, error) {
+	client := newHTTPClient(getRequestTimeout())
+
+	resp, err := client.Get(fmt.Sprintf(ghcrTokenURL, url.PathEscape(repo)))
+	if err != nil {
+		return nil, fmt.Errorf("error fetching ghcr.io token: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch ghcr.io token: status code %d", resp.StatusCode)
+	}
+	var token struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return nil, fmt.Errorf("error decoding ghcr.io token: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf(ghcrTagsURL, repo), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token.Token)
+	tagsResp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching ghcr.io tags: %w", err)
+	}
+	defer tagsResp.Body.Close()
+	if tagsResp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch ghcr.io tags: status code %d", tagsResp.StatusCode)
+	}
+	var list struct {
+		Tags []string `json:"tags"`
+	}
+	if err := json.NewDecoder(tagsResp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("error decoding ghcr.io tags: %w", err)
+	}
+
+	// images are also tagged latest, edge or with commit hashes
+	var tags []string
+	for _, tag := range list.Tags {
+		if _, ok := parseVersion(tag); ok {
+			tags = append(tags, tag)
+		}
+	}
+	return sortVersions(tags), nil
+}
+
+// sortVersions sorts vX.Y.Z versions newest first
+func sortVersions(versions []string) []string {
+	sorted := append([]string(nil), versions...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return compareVersions(sorted[i], sorted[j]) > 0
+	})
+	return sorted
+}
+
+// versionSelectLimit is how many releases the version selector offers
+const versionSelectLimit = 10
+
+type versionItem struct {
+	Tag  string
+	Note string
+	URL  string
+}
+
+// selectVersion lets the user pick one of the latest releases of repo, the
+// latest release is used when there is no terminal to ask on
+func selectVersion(repo, installed string) (string, error) {
+	if !isInteractive() {
+		logInfo("No version specified, pulling latest version")
+		return getLatestRepoReleaseTag(repo)
+	}
+
+	tags, err := getRepoReleaseTags(repo)
+	if err != nil {
+		return "", err
+	}
+	tags = sortVersions(tags)
+	if len(tags) == 0 {
+		return "", fmt.Errorf("no releases found for %s", repo)
+	}
+	if len(tags) > versionSelectLimit {
+		tags = tags[:versionSelectLimit]
+	}
+
+	var items []versionItem
+	for i, tag := range tags {
+		item := versionItem{Tag: tag, URL: fmt.Sprintf("https://github.com/%s/releases/tag/%s", repo, tag)}
+		switch {
+		case tag == installed:
+			item.Note = "(installed)"
+		case i == 0:
+			item.Note = "(latest)"
+		}
+		items = append(items, item)
+	}
+
+	prompt := promptui.Select{
+		Label: "Version",
+		Items: items,
+		Templates: &promptui.SelectTemplates{
+			Active:   "▸ {{ .Tag | cyan }} {{ .Note | faint }}",
+			Inactive: "  {{ .Tag }} {{ .Note | faint }}",
+			Selected: "Version: {{ .Tag }}",
+			Details:  "Changelog: {{ .URL }}",
+		},
+	}
+	index, _, err := prompt.Run()
+	if err != nil {
+		return "", fmt.Errorf("prompt failed: %w", err)
+	}
+	return items[index].Tag, nil
+}